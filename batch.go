@@ -0,0 +1,205 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// Canonicalize parses, deduplicates and sorts a list of KRN strings.
+//
+// Every input is parsed with Parse. Inputs that parse to the same canonical
+// string are kept once. The resulting KRNs are sorted with CompareGrouped
+// (by service, then tree order, then version).
+//
+// The error slice holds one entry per input that failed to parse, in input order.
+// Each error names the input's index and value and wraps the Parse error, so
+// errors.Is works with the package's sentinel errors. It is nil if every input parsed.
+func Canonicalize(inputs []string) ([]*KRN, []error) {
+	var errs []error
+	seen := make(map[string]struct{}, len(inputs))
+	krns := make([]*KRN, 0, len(inputs))
+
+	for i, s := range inputs {
+		k, err := Parse(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("input %d (%q): %w", i, s, err))
+			continue
+		}
+
+		key := k.String()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		krns = append(krns, k)
+	}
+
+	slices.SortFunc(krns, CompareGrouped)
+	return krns, errs
+}
+
+// ParseAll parses every input with Parse and reports all failures at once.
+//
+// The returned slice has one entry per input, in input order; entries for
+// inputs that failed to parse are nil, so results can be mapped back to their
+// rows. The error joins (via errors.Join) one error per failing input, each
+// naming the input's index and value and wrapping the Parse error, so errors.Is
+// works with the package's sentinel errors. The error is nil if every input parsed.
+func ParseAll(inputs []string) ([]*KRN, error) {
+	krns := make([]*KRN, len(inputs))
+	var errs []error
+
+	for i, s := range inputs {
+		k, err := Parse(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("input %d (%q): %w", i, s, err))
+			continue
+		}
+		krns[i] = k
+	}
+
+	return krns, errors.Join(errs...)
+}
+
+// SplitList parses a list of KRNs stored in a single string, separated by
+// commas and/or whitespace, e.g. a legacy column holding
+// "//kopexa.com/tenants/a, //kopexa.com/tenants/b". Empty elements are skipped.
+// The KRNs are returned in order; an empty list returns nil.
+//
+// SplitList stops at the first invalid element and returns an error naming its
+// 0-based index and value and wrapping the Parse error, so errors.Is works
+// with the package's sentinel errors.
+func SplitList(s string) ([]*KRN, error) {
+	elems := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	var krns []*KRN
+	for i, elem := range elems {
+		k, err := Parse(elem)
+		if err != nil {
+			return nil, fmt.Errorf("element %d (%q): %w", i, elem, err)
+		}
+		krns = append(krns, k)
+	}
+	return krns, nil
+}
+
+// NormalizeAll normalizes every input with Normalize, in parallel on a pool of
+// runtime.GOMAXPROCS(0) workers.
+//
+// The result is deterministic despite the concurrency: the returned slice has
+// one entry per input, in input order, with "" for inputs that failed, and the
+// error slice holds one error per failing input, in input order. Each error
+// names the input's index and value and wraps the Normalize error, so errors.Is
+// works with the package's sentinel errors. It is nil if every input normalized.
+func NormalizeAll(inputs []string) ([]string, []error) {
+	out := make([]string, len(inputs))
+	failures := make([]error, len(inputs))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(inputs) {
+					return
+				}
+				normalized, err := Normalize(inputs[i])
+				if err != nil {
+					failures[i] = fmt.Errorf("input %d (%q): %w", i, inputs[i], err)
+					continue
+				}
+				out[i] = normalized
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, err := range failures {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return out, errs
+}
+
+// RewriteService returns a new slice in which every KRN whose service equals
+// from is replaced by k.WithService(to), e.g. to migrate resources from the
+// catalog service to isms. An empty from matches KRNs without a service.
+// Other KRNs, including nil entries, are copied to the result unchanged.
+// The input slice is not modified. Returns an error wrapping ErrInvalidService
+// (and thus ErrInvalidDomain) if to is not a valid service name.
+func RewriteService(ks []*KRN, from, to string) ([]*KRN, error) {
+	if !IsValidService(to) {
+		return nil, serviceError(to)
+	}
+
+	out := make([]*KRN, len(ks))
+	for i, k := range ks {
+		if k == nil || k.service != from {
+			out[i] = k
+			continue
+		}
+		newSegments := make([]Segment, len(k.segments))
+		copy(newSegments, k.segments)
+
+		out[i] = &KRN{
+			domain:   k.domain,
+			service:  to,
+			segments: newSegments,
+			version:  k.version,
+			query:    k.query,
+		}
+	}
+	return out, nil
+}
+
+// ParseReader lazily parses newline-delimited KRNs from r, one per line, without
+// reading all of r into memory.
+//
+// Surrounding whitespace is trimmed and blank lines are skipped. For each line
+// that fails to parse, the iterator yields a nil KRN and an error naming the
+// 1-based line number and wrapping the Parse error; iteration then continues
+// with the next line. A read error is yielded once and ends the iteration.
+// Lines longer than bufio.MaxScanTokenSize (64 KiB) are reported as read errors.
+func ParseReader(r io.Reader) iter.Seq2[*KRN, error] {
+	return func(yield func(*KRN, error) bool) {
+		scanner := bufio.NewScanner(r)
+		line := 0
+		for scanner.Scan() {
+			line++
+			s := strings.TrimSpace(scanner.Text())
+			if s == "" {
+				continue
+			}
+
+			k, err := Parse(s)
+			if err != nil {
+				err = fmt.Errorf("line %d: %w", line, err)
+			}
+			if !yield(k, err) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("line %d: %w", line+1, err))
+		}
+	}
+}
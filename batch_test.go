@@ -0,0 +1,407 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	inputs := []string{
+		"//isms.kopexa.com/tenants/acme",
+		"//kopexa.com/frameworks/nist",
+		"invalid",
+		"//kopexa.com/frameworks/iso27001@v2",
+		"//kopexa.com/frameworks/nist",
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/-bad",
+		"//isms.kopexa.com/tenants/acme",
+	}
+
+	krns, errs := Canonicalize(inputs)
+
+	want := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001@v2",
+		"//kopexa.com/frameworks/nist",
+		"//isms.kopexa.com/tenants/acme",
+	}
+	if len(krns) != len(want) {
+		t.Fatalf("got %d KRNs, want %d: %v", len(krns), len(want), krns)
+	}
+	for i, w := range want {
+		if krns[i].String() != w {
+			t.Errorf("position %d: got %s, want %s", i, krns[i], w)
+		}
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	if !errors.Is(errs[0], ErrInvalidKRN) || !strings.Contains(errs[0].Error(), "input 2") {
+		t.Errorf("unexpected first error: %v", errs[0])
+	}
+	if !errors.Is(errs[1], ErrInvalidResourceID) || !strings.Contains(errs[1].Error(), "input 6") {
+		t.Errorf("unexpected second error: %v", errs[1])
+	}
+}
+
+func TestCanonicalize_AllValid(t *testing.T) {
+	krns, errs := Canonicalize([]string{"//kopexa.com/frameworks/iso27001"})
+	if errs != nil {
+		t.Errorf("expected nil errors, got %v", errs)
+	}
+	if len(krns) != 1 {
+		t.Errorf("expected 1 KRN, got %d", len(krns))
+	}
+}
+
+func TestCanonicalize_Empty(t *testing.T) {
+	krns, errs := Canonicalize(nil)
+	if len(krns) != 0 || errs != nil {
+		t.Errorf("expected empty result, got %v, %v", krns, errs)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	inputs := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"invalid",
+		"//isms.kopexa.com/tenants/acme@v1",
+		"//kopexa.com/frameworks/-bad",
+		"",
+	}
+
+	krns, err := ParseAll(inputs)
+	if len(krns) != len(inputs) {
+		t.Fatalf("got %d results, want %d", len(krns), len(inputs))
+	}
+
+	want := []string{"//kopexa.com/frameworks/iso27001", "", "//isms.kopexa.com/tenants/acme@v1", "", ""}
+	for i, w := range want {
+		switch {
+		case w == "" && krns[i] != nil:
+			t.Errorf("position %d: expected nil, got %s", i, krns[i])
+		case w != "" && (krns[i] == nil || krns[i].String() != w):
+			t.Errorf("position %d: got %v, want %s", i, krns[i], w)
+		}
+	}
+
+	for _, sentinel := range []error{ErrInvalidKRN, ErrInvalidResourceID, ErrEmptyKRN} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("expected joined error to match %v", sentinel)
+		}
+	}
+	for _, s := range []string{`input 1 ("invalid")`, `input 3 ("//kopexa.com/frameworks/-bad")`, `input 4 ("")`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected error to mention %s, got %q", s, err.Error())
+		}
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 3 {
+		t.Errorf("expected 3 joined errors, got %v", err)
+	}
+}
+
+func TestParseAll_AllValid(t *testing.T) {
+	krns, err := ParseAll([]string{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/nist"})
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+	if len(krns) != 2 || krns[0] == nil || krns[1] == nil {
+		t.Errorf("unexpected result: %v", krns)
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	inputs := []string{
+		"  //Catalog.Kopexa.com//frameworks/iso27001/  ",
+		"invalid",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1",
+		"//kopexa.com/frameworks/-bad",
+		"",
+	}
+
+	got, errs := NormalizeAll(inputs)
+	want := []string{
+		"//catalog.kopexa.com/frameworks/iso27001",
+		"",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1",
+		"",
+		"",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("NormalizeAll() = %q, want %q", got, want)
+	}
+
+	wantErrs := []error{ErrInvalidKRN, ErrInvalidResourceID, ErrEmptyKRN}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(wantErrs), errs)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, wantErrs[i]) {
+			t.Errorf("error %d: expected %v, got %v", i, wantErrs[i], err)
+		}
+	}
+	if !strings.HasPrefix(errs[0].Error(), `input 1 ("invalid"):`) {
+		t.Errorf("unexpected error message: %v", errs[0])
+	}
+}
+
+func TestNormalizeAll_Deterministic(t *testing.T) {
+	inputs := make([]string, 1000)
+	for i := range inputs {
+		if i%7 == 0 {
+			inputs[i] = fmt.Sprintf("//kopexa.com/evidences/-bad-%d", i)
+			continue
+		}
+		inputs[i] = fmt.Sprintf("//KOPEXA.com/evidences/ev-%d", i)
+	}
+
+	first, firstErrs := NormalizeAll(inputs)
+	for range 5 {
+		got, errs := NormalizeAll(inputs)
+		if !slices.Equal(got, first) {
+			t.Fatal("results differ between runs")
+		}
+		if fmt.Sprint(errs) != fmt.Sprint(firstErrs) {
+			t.Fatal("errors differ between runs")
+		}
+	}
+
+	for i, s := range first {
+		want := ""
+		if i%7 != 0 {
+			want = fmt.Sprintf("//kopexa.com/evidences/ev-%d", i)
+		}
+		if s != want {
+			t.Fatalf("result %d = %q, want %q", i, s, want)
+		}
+	}
+	if len(firstErrs) != 143 {
+		t.Errorf("got %d errors, want 143", len(firstErrs))
+	}
+}
+
+func TestNormalizeAll_Empty(t *testing.T) {
+	got, errs := NormalizeAll(nil)
+	if len(got) != 0 || errs != nil {
+		t.Errorf("NormalizeAll(nil) = (%v, %v)", got, errs)
+	}
+}
+
+func BenchmarkNormalizeAll(b *testing.B) {
+	inputs := make([]string, 10000)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("//Catalog.kopexa.com/tenants/t-%d/workspaces/main/evidences/ev-%d@v1", i%100, i)
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, s := range inputs {
+				_, _ = Normalize(s)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = NormalizeAll(inputs)
+		}
+	})
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"commas", "//kopexa.com/tenants/a,//kopexa.com/tenants/b", []string{"//kopexa.com/tenants/a", "//kopexa.com/tenants/b"}},
+		{"commas and spaces", "//kopexa.com/tenants/a, //isms.kopexa.com/tenants/b@v1", []string{"//kopexa.com/tenants/a", "//isms.kopexa.com/tenants/b@v1"}},
+		{"whitespace", "//kopexa.com/tenants/a\n\t//kopexa.com/tenants/b  //kopexa.com/tenants/c", []string{"//kopexa.com/tenants/a", "//kopexa.com/tenants/b", "//kopexa.com/tenants/c"}},
+		{"empty elements", ",, //kopexa.com/tenants/a ,,", []string{"//kopexa.com/tenants/a"}},
+		{"empty", "", nil},
+		{"separators only", " , ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			krns, err := SplitList(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, k := range krns {
+				got = append(got, k.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid element", func(t *testing.T) {
+		krns, err := SplitList("//kopexa.com/tenants/a, //kopexa.com/tenants/-b, bogus")
+		if krns != nil || !errors.Is(err, ErrInvalidResourceID) {
+			t.Fatalf("expected ErrInvalidResourceID, got %v, %v", krns, err)
+		}
+		if want := `element 1 ("//kopexa.com/tenants/-b")`; !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("expected error to start with %s, got %v", want, err)
+		}
+	})
+}
+
+func TestRewriteService(t *testing.T) {
+	ks := []*KRN{
+		MustParse("//catalog.kopexa.com/frameworks/iso27001@v1"),
+		MustParse("//kopexa.com/frameworks/nist"),
+		nil,
+		MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1"),
+		MustParse("//risk.kopexa.com/risks/r-1"),
+	}
+
+	got, err := RewriteService(ks, "catalog", "isms")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"//isms.kopexa.com/frameworks/iso27001@v1",
+		"//kopexa.com/frameworks/nist",
+		"",
+		"//isms.kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//risk.kopexa.com/risks/r-1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d KRNs, want %d", len(got), len(want))
+	}
+	for i, k := range got {
+		if want[i] == "" {
+			if k != nil {
+				t.Errorf("entry %d: expected nil, got %s", i, k)
+			}
+			continue
+		}
+		if k.String() != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, k, want[i])
+		}
+	}
+	if ks[0].String() != "//catalog.kopexa.com/frameworks/iso27001@v1" {
+		t.Errorf("input was modified: %s", ks[0])
+	}
+
+	t.Run("keeps the query", func(t *testing.T) {
+		k, _ := ParseWithOptions("//catalog.kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+		got, err := RewriteService([]*KRN{k}, "catalog", "isms")
+		if err != nil || got[0].String() != "//isms.kopexa.com/frameworks/iso27001?region=eu" {
+			t.Errorf("got (%v, %v)", got, err)
+		}
+	})
+
+	t.Run("empty from matches unserviced KRNs", func(t *testing.T) {
+		got, err := RewriteService(ks[:2], "", "catalog")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got[0] != ks[0] || got[1].String() != "//catalog.kopexa.com/frameworks/nist" {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("invalid target service", func(t *testing.T) {
+		for _, to := range []string{"", "Isms", "bad_service"} {
+			got, err := RewriteService(ks, "catalog", to)
+			if !errors.Is(err, ErrInvalidDomain) || !errors.Is(err, ErrInvalidService) {
+				t.Errorf("RewriteService(%q): expected ErrInvalidService, got %v", to, err)
+			}
+			if got != nil {
+				t.Errorf("RewriteService(%q): expected nil result, got %v", to, got)
+			}
+		}
+	})
+}
+
+func TestParseReader(t *testing.T) {
+	input := strings.Join([]string{
+		"//kopexa.com/frameworks/iso27001",
+		"",
+		"  //isms.kopexa.com/tenants/acme@v1  ",
+		"invalid",
+		"   ",
+		"//kopexa.com/frameworks/-bad",
+		"//kopexa.com/frameworks/nist",
+	}, "\n")
+
+	var got []string
+	var errs []error
+	for k, err := range ParseReader(strings.NewReader(input)) {
+		if err != nil {
+			if k != nil {
+				t.Errorf("expected nil KRN with error, got %s", k)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, k.String())
+	}
+
+	want := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//isms.kopexa.com/tenants/acme@v1",
+		"//kopexa.com/frameworks/nist",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	if !errors.Is(errs[0], ErrInvalidKRN) || !strings.HasPrefix(errs[0].Error(), "line 4:") {
+		t.Errorf("unexpected first error: %v", errs[0])
+	}
+	if !errors.Is(errs[1], ErrInvalidResourceID) || !strings.HasPrefix(errs[1].Error(), "line 6:") {
+		t.Errorf("unexpected second error: %v", errs[1])
+	}
+}
+
+func TestParseReader_EarlyBreak(t *testing.T) {
+	input := "//kopexa.com/frameworks/a\n//kopexa.com/frameworks/b\n//kopexa.com/frameworks/c\n"
+	count := 0
+	for range ParseReader(strings.NewReader(input)) {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected 2 iterations, got %d", count)
+	}
+
+	for _, err := range ParseReader(strings.NewReader("invalid\n//kopexa.com/frameworks/a")) {
+		if err == nil {
+			t.Error("expected error first")
+		}
+		break
+	}
+}
+
+func TestParseReader_ReadError(t *testing.T) {
+	input := "//kopexa.com/frameworks/a\n" + strings.Repeat("x", 70*1024) + "\n"
+
+	var errs []error
+	for _, err := range ParseReader(strings.NewReader(input)) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "line 2:") {
+		t.Errorf("expected one read error on line 2, got %v", errs)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/url"
+)
+
+// binaryFormat is the first byte of the MarshalBinary encoding, so the layout
+// can evolve without misreading stored values.
+const binaryFormat = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler with a compact encoding for
+// caches and key-value stores. Instead of the full string it stores a format
+// byte followed by length-prefixed fields (uvarint length, then the bytes):
+// domain (empty for the default Domain), service, the number of segments as a
+// uvarint, each segment's collection and resource ID, version and query.
+// A nil KRN returns ErrEmptyKRN.
+func (k *KRN) MarshalBinary() ([]byte, error) {
+	if k == nil {
+		return nil, ErrEmptyKRN
+	}
+
+	b := make([]byte, 0, k.Len())
+	b = append(b, binaryFormat)
+	b = appendBinaryField(b, k.domain)
+	b = appendBinaryField(b, k.service)
+	b = binary.AppendUvarint(b, uint64(len(k.segments)))
+	for _, seg := range k.segments {
+		b = appendBinaryField(b, seg.Collection)
+		b = appendBinaryField(b, seg.ResourceID)
+	}
+	b = appendBinaryField(b, k.version)
+	b = appendBinaryField(b, k.query)
+	return b, nil
+}
+
+// appendBinaryField appends s with its uvarint length prefix.
+func appendBinaryField(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler and decodes data
+// produced by MarshalBinary without running the string parser. Every field is
+// still validated with the rules of Parse, so corrupted or forged data cannot
+// produce a KRN that Parse would reject. Empty data returns ErrEmptyKRN;
+// malformed data returns ErrInvalidKRN or the sentinel error of the invalid
+// field. On error k is left unchanged.
+func (k *KRN) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrEmptyKRN
+	}
+	if data[0] != binaryFormat {
+		return fmt.Errorf("%w: unsupported binary format %d", ErrInvalidKRN, data[0])
+	}
+
+	// Fields are substrings of one copy of data, so decoding allocates once
+	// for all strings.
+	d := binaryDecoder{data: string(data[1:])}
+	decoded := KRN{domain: d.field(), service: d.field()}
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.data)/2) {
+		// Every segment takes at least two bytes; reject before allocating.
+		d.err = fmt.Errorf("%w: binary KRN claims %d segments in %d bytes", ErrInvalidKRN, n, len(d.data))
+	}
+	if d.err != nil {
+		return d.err
+	}
+	decoded.segments = make([]Segment, n)
+	for i := range decoded.segments {
+		decoded.segments[i] = Segment{Collection: d.field(), ResourceID: d.field()}
+	}
+	decoded.version = d.field()
+	decoded.query = d.field()
+
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes in binary KRN", ErrInvalidKRN, len(d.data))
+	}
+	if err := decoded.validateDecoded(); err != nil {
+		return err
+	}
+
+	*k = decoded
+	return nil
+}
+
+// binaryDecoder reads MarshalBinary fields. The first error sticks; later reads
+// return zero values.
+type binaryDecoder struct {
+	data string
+	err  error
+}
+
+// uvarint reads a uvarint as written by binary.AppendUvarint.
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	for i := 0; i < len(d.data) && i < binary.MaxVarintLen64; i++ {
+		c := d.data[i]
+		if i == binary.MaxVarintLen64-1 && c > 1 {
+			break // overflows 64 bits
+		}
+		v |= uint64(c&0x7f) << (7 * i)
+		if c < 0x80 {
+			d.data = d.data[i+1:]
+			return v
+		}
+	}
+	d.err = fmt.Errorf("%w: truncated or overlong varint in binary KRN", ErrInvalidKRN)
+	return 0
+}
+
+// field reads a length-prefixed string.
+func (d *binaryDecoder) field() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.data)) {
+		d.err = fmt.Errorf("%w: truncated binary KRN", ErrInvalidKRN)
+		return ""
+	}
+	s := d.data[:n]
+	d.data = d.data[n:]
+	return s
+}
+
+// validateDecoded checks a KRN decoded by UnmarshalBinary against the rules of
+// Parse. The default Domain is normalized to the empty domain.
+func (k *KRN) validateDecoded() error {
+	if k.domain == Domain {
+		k.domain = ""
+	}
+	if k.domain != "" && !isValidDomain(k.domain) {
+		return fmt.Errorf("%w: invalid base domain %q", ErrInvalidDomain, k.domain)
+	}
+	if k.service != "" && !IsValidService(k.service) {
+		return serviceError(k.service)
+	}
+	if len(k.segments) == 0 {
+		return fmt.Errorf("%w: must have at least one collection/id", ErrInvalidKRN)
+	}
+	for _, seg := range k.segments {
+		if err := validateSegment(seg); err != nil {
+			return err
+		}
+	}
+	if k.version != "" && !IsValidVersion(k.version) {
+		return fmt.Errorf("%w: %s", ErrInvalidVersion, k.version)
+	}
+	if q, err := url.ParseQuery(k.query); err != nil || q.Encode() != k.query {
+		return fmt.Errorf("%w: invalid query %q", ErrInvalidKRN, k.query)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"encoding"
+	"errors"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*KRN)(nil)
+	_ encoding.BinaryUnmarshaler = (*KRN)(nil)
+)
+
+func TestKRN_Binary(t *testing.T) {
+	dev, _ := ParseWithOptions("//isms.kopexa.dev/tenants/acme@v1", WithDomain("kopexa.dev"))
+	withQuery, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+
+	inputs := []*KRN{
+		MustParse("//kopexa.com/frameworks/iso27001"),
+		MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.3"),
+		MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev_1@latest"),
+		dev,
+		withQuery,
+	}
+
+	for _, k := range inputs {
+		t.Run(k.String(), func(t *testing.T) {
+			data, err := k.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary error: %v", err)
+			}
+			if k.domain == "" && len(data) >= k.Len() {
+				t.Errorf("binary form has %d bytes, string form %d", len(data), k.Len())
+			}
+
+			var decoded KRN
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary error: %v", err)
+			}
+			if !decoded.Equals(k) {
+				t.Errorf("round trip = %s, want %s", &decoded, k)
+			}
+		})
+	}
+
+	t.Run("layout", func(t *testing.T) {
+		data, _ := MustParse("//catalog.kopexa.com/frameworks/iso27001@v1").MarshalBinary()
+		want := "\x01\x00\x07catalog\x01\x0aframeworks\x08iso27001\x02v1\x00"
+		if string(data) != want {
+			t.Errorf("MarshalBinary() = %q, want %q", data, want)
+		}
+	})
+
+	t.Run("default domain is normalized", func(t *testing.T) {
+		var k KRN
+		err := k.UnmarshalBinary([]byte("\x01\x0akopexa.com\x00\x01\x0aframeworks\x08iso27001\x00\x00"))
+		if err != nil || k.String() != "//kopexa.com/frameworks/iso27001" || k.domain != "" {
+			t.Errorf("UnmarshalBinary() = (%s, %v)", &k, err)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var k *KRN
+		if _, err := k.MarshalBinary(); !errors.Is(err, ErrEmptyKRN) {
+			t.Errorf("expected ErrEmptyKRN, got %v", err)
+		}
+	})
+}
+
+func TestKRN_UnmarshalBinary_Errors(t *testing.T) {
+	// A valid encoding of //catalog.kopexa.com/frameworks/iso27001@v1.
+	const valid = "\x01\x00\x07catalog\x01\x0aframeworks\x08iso27001\x02v1\x00"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{"empty", "", ErrEmptyKRN},
+		{"unknown format", "\x02" + valid[1:], ErrInvalidKRN},
+		{"truncated", valid[:len(valid)-3], ErrInvalidKRN},
+		{"truncated varint", "\x01\x00\x07catalog\x80", ErrInvalidKRN},
+		{"overlong varint", "\x01\x00\x00\xff\xff\xff\xff\xff\xff\xff\xff\xff\x02", ErrInvalidKRN},
+		{"trailing bytes", valid + "x", ErrInvalidKRN},
+		{"too many segments", "\x01\x00\x00\xff\xff\x03", ErrInvalidKRN},
+		{"no segments", "\x01\x00\x00\x00\x00\x00", ErrInvalidKRN},
+		{"invalid domain", "\x01\x03a b\x00\x01\x01a\x01b\x00\x00", ErrInvalidDomain},
+		{"invalid service", "\x01\x00\x03A-B\x01\x01a\x01b\x00\x00", ErrInvalidService},
+		{"empty collection", "\x01\x00\x00\x01\x00\x01b\x00\x00", ErrInvalidKRN},
+		{"slash in collection", "\x01\x00\x00\x01\x03a/b\x01b\x00\x00", ErrInvalidKRN},
+		{"invalid resource ID", "\x01\x00\x00\x01\x01a\x02-b\x00\x00", ErrInvalidResourceID},
+		{"invalid version", "\x01\x00\x00\x01\x01a\x01b\x02-1\x00", ErrInvalidVersion},
+		{"non-canonical query", "\x01\x00\x00\x01\x01a\x01b\x00\x07b=1&a=2", ErrInvalidKRN},
+		{"malformed query", "\x01\x00\x00\x01\x01a\x01b\x00\x03a=%", ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MustParse("//kopexa.com/frameworks/unchanged")
+			if err := k.UnmarshalBinary([]byte(tt.data)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if k.String() != "//kopexa.com/frameworks/unchanged" {
+				t.Errorf("KRN modified on error: %s", k)
+			}
+		})
+	}
+}
+
+func BenchmarkKRN_Decode(b *testing.B) {
+	k := MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v1")
+	text, _ := k.MarshalText()
+	data, _ := k.MarshalBinary()
+
+	b.Run("text", func(b *testing.B) {
+		var decoded KRN
+		for i := 0; i < b.N; i++ {
+			_ = decoded.UnmarshalText(text)
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		var decoded KRN
+		for i := 0; i < b.N; i++ {
+			_ = decoded.UnmarshalBinary(data)
+		}
+	})
+}
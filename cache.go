@@ -0,0 +1,118 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultParseCacheSize is the initial capacity of the ParseCached cache.
+const DefaultParseCacheSize = 4096
+
+// parseCache backs ParseCached.
+var parseCache = newLRUCache(DefaultParseCacheSize)
+
+// ParseCached parses s like Parse, but serves repeated inputs from a bounded,
+// package-level LRU cache shared by all goroutines. It is meant for hot paths
+// that see the same KRNs over and over, such as request handlers.
+//
+// Cached KRNs are shared between callers and must be treated as immutable; do
+// not pass them to UnmarshalJSON, UnmarshalText, UnmarshalYAML,
+// UnmarshalBinary or Scan. Invalid input is not cached and returns the Parse
+// error.
+//
+// The cache holds at most DefaultParseCacheSize KRNs unless changed with
+// SetParseCacheSize. When it is full, the least recently used KRN is evicted.
+func ParseCached(s string) (*KRN, error) {
+	if k, ok := parseCache.get(s); ok {
+		return k, nil
+	}
+
+	k, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	parseCache.add(s, k)
+	return k, nil
+}
+
+// SetParseCacheSize sets the maximum number of KRNs held by the ParseCached
+// cache, evicting the least recently used entries if it shrinks.
+// A size of 0 or less disables caching, so ParseCached behaves like Parse.
+// It is safe to call concurrently with ParseCached.
+func SetParseCacheSize(n int) {
+	parseCache.resize(max(n, 0))
+}
+
+// lruCache is a concurrency-safe least-recently-used cache of parsed KRNs
+// keyed by the raw input string, so that a hit needs no parsing. Inputs that
+// parse to the same KRN take separate entries.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List // front is most recently used; values are *lruEntry
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	krn *KRN
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (*KRN, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).krn, true
+}
+
+func (c *lruCache) add(key string, k *KRN) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size == 0 {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, krn: k})
+	c.evict()
+}
+
+func (c *lruCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	c.evict()
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// evict drops least recently used entries until the cache fits its size.
+// The caller must hold c.mu.
+func (c *lruCache) evict() {
+	for c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// resetParseCache restores the default ParseCached cache after a test.
+func resetParseCache(t *testing.T) {
+	t.Helper()
+	parseCache = newLRUCache(DefaultParseCacheSize)
+	t.Cleanup(func() {
+		parseCache = newLRUCache(DefaultParseCacheSize)
+	})
+}
+
+func TestParseCached(t *testing.T) {
+	resetParseCache(t)
+	const input = "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1"
+
+	a, err := ParseCached(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.String() != input {
+		t.Errorf("ParseCached() = %s, want %s", a, input)
+	}
+	b, _ := ParseCached(input)
+	if a != b {
+		t.Error("expected repeated input to be served from the cache")
+	}
+
+	if _, err := ParseCached("//kopexa.com/frameworks/-bad"); !errors.Is(err, ErrInvalidResourceID) {
+		t.Errorf("expected ErrInvalidResourceID, got %v", err)
+	}
+	if parseCache.len() != 1 {
+		t.Errorf("invalid input was cached: len = %d", parseCache.len())
+	}
+}
+
+func TestParseCached_Eviction(t *testing.T) {
+	resetParseCache(t)
+	SetParseCacheSize(2)
+
+	a, _ := ParseCached("//kopexa.com/frameworks/a")
+	_, _ = ParseCached("//kopexa.com/frameworks/b")
+	_, _ = ParseCached("//kopexa.com/frameworks/a") // a is now most recently used
+	_, _ = ParseCached("//kopexa.com/frameworks/c") // evicts b
+
+	if parseCache.len() != 2 {
+		t.Fatalf("len = %d, want 2", parseCache.len())
+	}
+	if got, _ := ParseCached("//kopexa.com/frameworks/a"); got != a {
+		t.Error("expected a to survive eviction")
+	}
+	if _, ok := parseCache.get("//kopexa.com/frameworks/b"); ok {
+		t.Error("expected b to be evicted")
+	}
+
+	SetParseCacheSize(1)
+	if parseCache.len() != 1 {
+		t.Errorf("len after shrinking = %d, want 1", parseCache.len())
+	}
+	if _, ok := parseCache.get("//kopexa.com/frameworks/a"); !ok {
+		t.Error("expected the most recently used entry to survive shrinking")
+	}
+}
+
+func TestLRUCache_AddExisting(t *testing.T) {
+	c := newLRUCache(2)
+	first := MustParse("//kopexa.com/frameworks/a")
+	c.add("//kopexa.com/frameworks/a", first)
+	c.add("//kopexa.com/frameworks/b", MustParse("//kopexa.com/frameworks/b"))
+	// A concurrent miss may add the same key again; the pooled KRN is kept.
+	c.add("//kopexa.com/frameworks/a", MustParse("//kopexa.com/frameworks/a"))
+	c.add("//kopexa.com/frameworks/c", MustParse("//kopexa.com/frameworks/c"))
+
+	if got, ok := c.get("//kopexa.com/frameworks/a"); !ok || got != first {
+		t.Error("expected the first KRN for a to be kept and refreshed")
+	}
+	if _, ok := c.get("//kopexa.com/frameworks/b"); ok {
+		t.Error("expected b to be evicted")
+	}
+}
+
+func TestParseCached_Disabled(t *testing.T) {
+	resetParseCache(t)
+	SetParseCacheSize(-1)
+
+	a, err := ParseCached("//kopexa.com/frameworks/a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := ParseCached("//kopexa.com/frameworks/a")
+	if a == b || !a.Equals(b) {
+		t.Error("expected fresh, equal KRNs with caching disabled")
+	}
+	if parseCache.len() != 0 {
+		t.Errorf("len = %d, want 0", parseCache.len())
+	}
+}
+
+func TestParseCached_Concurrent(t *testing.T) {
+	resetParseCache(t)
+	SetParseCacheSize(16)
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				s := fmt.Sprintf("//kopexa.com/evidences/ev-%d", (i+w)%32)
+				k, err := ParseCached(s)
+				if err != nil || k.String() != s {
+					t.Errorf("ParseCached(%q) = (%v, %v)", s, k, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if parseCache.len() > 16 {
+		t.Errorf("len = %d, exceeds size 16", parseCache.len())
+	}
+}
+
+func BenchmarkParseCached(b *testing.B) {
+	parseCache = newLRUCache(DefaultParseCacheSize)
+	const input = "//kopexa.com/tenants/acme-corp/control-implementations/ci-123/evidences/ev-456@v1.2.3"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = ParseCached(input)
+	}
+}
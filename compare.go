@@ -0,0 +1,186 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sameSegments reports whether a and b contain the same collection/resource-id pairs in order.
+func sameSegments(a, b []Segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameHost reports whether a and b have the same base domain and service.
+func sameHost(a, b *KRN) bool {
+	return a.domain == b.domain && a.service == b.service
+}
+
+// CompareGrouped orders KRNs grouped by service and then in tree order.
+// It returns -1 if a sorts before b, +1 if it sorts after, and 0 if they are equal.
+//
+// The ordering is:
+//   - nil sorts before any KRN,
+//   - by base domain, with the default Domain first (see WithDomain),
+//   - by service, with unserviced KRNs (the bare base domain) first,
+//   - segment by segment, comparing the collection and then the resource ID;
+//     an ancestor sorts directly before its descendants,
+//   - by version: unversioned < "draft" < semantic versions (numerically) <
+//     other versions (lexically) < "latest",
+//   - by canonical query (see WithQuery), with KRNs without a query first.
+//
+// CompareGrouped returns 0 exactly when a.Equals(b).
+func CompareGrouped(a, b *KRN) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if c := strings.Compare(a.domain, b.domain); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.service, b.service); c != 0 {
+		return c
+	}
+
+	for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+		if c := strings.Compare(a.segments[i].Collection, b.segments[i].Collection); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.segments[i].ResourceID, b.segments[i].ResourceID); c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(len(a.segments), len(b.segments)); c != 0 {
+		return c
+	}
+
+	if c := compareVersions(a.version, b.version); c != 0 {
+		return c
+	}
+	return strings.Compare(a.query, b.query)
+}
+
+// Compare orders k relative to other and returns -1, 0 or +1.
+// It uses the ordering of CompareGrouped: by service, then segment by segment
+// (collection, then resource ID), then by version and finally by query.
+// Unversioned KRNs sort before versioned ones, and a nil KRN sorts before any
+// non-nil KRN.
+func (k *KRN) Compare(other *KRN) int {
+	return CompareGrouped(k, other)
+}
+
+// SortKRNs sorts ks in place using Compare. Nil entries sort first.
+func SortKRNs(ks []*KRN) {
+	slices.SortFunc(ks, CompareGrouped)
+}
+
+// EqualsIgnoreVersion reports whether k and other denote the same resource
+// regardless of version, e.g. iso27001@v1 and iso27001@v2. It compares the base
+// domain, service and segments without allocating. Returns false if other is nil.
+func (k *KRN) EqualsIgnoreVersion(other *KRN) bool {
+	return other != nil && sameHost(k, other) && sameSegments(k.segments, other.segments)
+}
+
+// SameResource reports whether k and other have the same segment sequence,
+// ignoring the base domain, service and version, e.g. to map the same logical
+// resource across services or environments. Use EqualsIgnoreVersion when the
+// service matters. Returns false if other is nil.
+func (k *KRN) SameResource(other *KRN) bool {
+	return other != nil && sameSegments(k.segments, other.segments)
+}
+
+// EqualsFold is like Equals but compares resource IDs case-insensitively, for
+// interop with systems that change the case of IDs (ISO27001 vs iso27001).
+// The service, collections, version and query must match exactly.
+// Returns false if other is nil.
+func (k *KRN) EqualsFold(other *KRN) bool {
+	if other == nil || !sameHost(k, other) || k.version != other.version || k.query != other.query ||
+		len(k.segments) != len(other.segments) {
+		return false
+	}
+	for i := range k.segments {
+		if k.segments[i].Collection != other.segments[i].Collection ||
+			!strings.EqualFold(k.segments[i].ResourceID, other.segments[i].ResourceID) {
+			return false
+		}
+	}
+	return true
+}
+
+// SameShape reports whether k and other follow the same template: both have or
+// lack a service, have the same sequence of collections, and both have or lack a
+// version. Resource IDs, the service name and the version value are ignored.
+// Returns false if other is nil.
+func (k *KRN) SameShape(other *KRN) bool {
+	if other == nil || len(k.segments) != len(other.segments) {
+		return false
+	}
+	if (k.service == "") != (other.service == "") || (k.version == "") != (other.version == "") {
+		return false
+	}
+	for i := range k.segments {
+		if k.segments[i].Collection != other.segments[i].Collection {
+			return false
+		}
+	}
+	return true
+}
+
+// CanMerge reports whether a and b describe the same resource and can be merged.
+//
+// Two KRNs can be merged when:
+//   - both are non-nil,
+//   - they have the same base domain, service, segment path and query, and
+//   - their versions are equal, or at least one of them is unversioned.
+func CanMerge(a, b *KRN) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if !sameHost(a, b) || !sameSegments(a.segments, b.segments) || a.query != b.query {
+		return false
+	}
+	return a.version == b.version || a.version == "" || b.version == ""
+}
+
+// Merge combines two mergeable KRNs into a new KRN, keeping the more specific
+// version: if only one of them is versioned, the result carries that version.
+// The shared query is kept.
+// Returns ErrInvalidKRN if CanMerge(a, b) is false.
+func Merge(a, b *KRN) (*KRN, error) {
+	if !CanMerge(a, b) {
+		return nil, fmt.Errorf("%w: cannot merge %v and %v", ErrInvalidKRN, a, b)
+	}
+
+	version := a.version
+	if version == "" {
+		version = b.version
+	}
+
+	newSegments := make([]Segment, len(a.segments))
+	copy(newSegments, a.segments)
+
+	return &KRN{
+		domain:   a.domain,
+		service:  a.service,
+		segments: newSegments,
+		version:  version,
+		query:    a.query,
+	}, nil
+}
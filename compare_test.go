@@ -0,0 +1,432 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCanMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", true},
+		{"both unversioned", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", true},
+		{"left unversioned", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001@v2", true},
+		{"right unversioned", "//catalog.kopexa.com/frameworks/iso27001@v2", "//catalog.kopexa.com/frameworks/iso27001", true},
+		{"conflicting versions", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v2", false},
+		{"different service", "//catalog.kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", false},
+		{"different resource ID", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27002", false},
+		{"different collection", "//kopexa.com/frameworks/iso27001", "//kopexa.com/policies/iso27001", false},
+		{"different depth", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a-5-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := CanMerge(a, b); got != tt.want {
+				t.Errorf("CanMerge(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := CanMerge(b, a); got != tt.want {
+				t.Errorf("CanMerge(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("query", func(t *testing.T) {
+		eu, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+		euV1, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001@v1?region=eu", WithQuery())
+		us, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=us", WithQuery())
+		if !CanMerge(eu, euV1) {
+			t.Error("expected KRNs with the same query to be mergeable")
+		}
+		if CanMerge(eu, us) || CanMerge(eu, MustParse("//kopexa.com/frameworks/iso27001")) {
+			t.Error("expected KRNs with different queries not to be mergeable")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if CanMerge(nil, k) || CanMerge(k, nil) || CanMerge(nil, nil) {
+			t.Error("expected CanMerge with nil to be false")
+		}
+	})
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		want    string
+		wantErr error
+	}{
+		{"same version", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", nil},
+		{"takes right version", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001@v2", "//kopexa.com/frameworks/iso27001@v2", nil},
+		{"takes left version", "//isms.kopexa.com/tenants/acme@v3", "//isms.kopexa.com/tenants/acme", "//isms.kopexa.com/tenants/acme@v3", nil},
+		{"unversioned", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", nil},
+		{"conflict", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v2", "", ErrInvalidKRN},
+		{"different path", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/nist", "", ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			got, err := Merge(a, b)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Merge() = %q, want %q", got.String(), tt.want)
+			}
+			if got == a || got == b {
+				t.Error("expected a new KRN")
+			}
+		})
+	}
+
+	t.Run("query", func(t *testing.T) {
+		a, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+		b, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001@v1?region=eu", WithQuery())
+		got, err := Merge(a, b)
+		if err != nil || got.String() != "//kopexa.com/frameworks/iso27001@v1?region=eu" {
+			t.Errorf("Merge() = (%v, %v)", got, err)
+		}
+		if _, err := Merge(a, MustParse("//kopexa.com/frameworks/iso27001@v1")); !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if _, err := Merge(nil, MustParse("//kopexa.com/frameworks/iso27001")); !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+}
+
+func TestCompareGrouped(t *testing.T) {
+	// Expected order of a mixed-service admin list.
+	ordered := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//kopexa.com/frameworks/nist",
+		"//kopexa.com/tenants/acme",
+		"//catalog.kopexa.com/frameworks/iso27001",
+		"//catalog.kopexa.com/frameworks/iso27001@draft",
+		"//catalog.kopexa.com/frameworks/iso27001@v1.2.0",
+		"//catalog.kopexa.com/frameworks/iso27001@v1.10.0",
+		"//catalog.kopexa.com/frameworks/iso27001@latest",
+		"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-2",
+		"//catalog.kopexa.com/frameworks/iso27001/policies/p-1",
+		"//isms.kopexa.com/tenants/acme-corp",
+		"//isms.kopexa.com/tenants/acme-corp/workspaces/main",
+		"//isms.kopexa.com/tenants/globex",
+	}
+
+	krns := make([]*KRN, len(ordered))
+	for i, s := range ordered {
+		krns[i] = MustParse(s)
+	}
+
+	t.Run("pairwise", func(t *testing.T) {
+		for i := range krns {
+			for j := range krns {
+				if got, want := CompareGrouped(krns[i], krns[j]), cmp.Compare(i, j); got != want {
+					t.Errorf("CompareGrouped(%s, %s) = %d, want %d", krns[i], krns[j], got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("sorts shuffled list", func(t *testing.T) {
+		shuffled := []*KRN{krns[12], krns[4], krns[0], krns[14], krns[8], krns[2], krns[10], krns[6], krns[1], krns[13], krns[3], krns[11], krns[5], krns[9], krns[7]}
+		slices.SortFunc(shuffled, CompareGrouped)
+		for i, k := range shuffled {
+			if k != krns[i] {
+				t.Errorf("position %d: got %s, want %s", i, k, krns[i])
+			}
+		}
+	})
+
+	t.Run("query breaks ties", func(t *testing.T) {
+		plain := MustParse("//kopexa.com/frameworks/iso27001@v1")
+		eu, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001@v1?region=eu", WithQuery())
+		us, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001@v1?region=us", WithQuery())
+
+		ks := []*KRN{us, eu, plain}
+		SortKRNs(ks)
+		if ks[0] != plain || ks[1] != eu || ks[2] != us {
+			t.Errorf("SortKRNs() = %v", ks)
+		}
+		if eu.Compare(us) != -1 || us.Compare(eu) != 1 || eu.Compare(eu.Clone()) != 0 {
+			t.Error("expected KRNs that differ only in query to compare unequal")
+		}
+		if MustParse("//kopexa.com/frameworks/iso27001@v2").Compare(us) != 1 {
+			t.Error("expected the version to take precedence over the query")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if CompareGrouped(nil, nil) != 0 {
+			t.Error("expected nil == nil")
+		}
+		if CompareGrouped(nil, k) != -1 {
+			t.Error("expected nil before KRN")
+		}
+		if CompareGrouped(k, nil) != 1 {
+			t.Error("expected KRN after nil")
+		}
+	})
+}
+
+func TestKRN_Compare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"equal", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", 0},
+		{"service", "//kopexa.com/frameworks/nist", "//catalog.kopexa.com/frameworks/iso27001", -1},
+		{"collection", "//kopexa.com/policies/a", "//kopexa.com/frameworks/z", 1},
+		{"resource ID", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/nist", -1},
+		{"ancestor first", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a", -1},
+		{"unversioned first", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001@draft", -1},
+		{"versions numerically", "//kopexa.com/frameworks/iso27001@v10", "//kopexa.com/frameworks/iso27001@v9", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var nilKRN *KRN
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if k.Compare(nil) != 1 || nilKRN.Compare(k) != -1 || nilKRN.Compare(nil) != 0 {
+			t.Error("expected nil to sort first")
+		}
+	})
+}
+
+func TestSortKRNs(t *testing.T) {
+	ks := []*KRN{
+		MustParse("//kopexa.com/frameworks/nist"),
+		MustParse("//kopexa.com/frameworks/iso27001@v2"),
+		nil,
+		MustParse("//catalog.kopexa.com/frameworks/iso27001"),
+		MustParse("//kopexa.com/frameworks/iso27001"),
+		MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1"),
+	}
+	want := []string{
+		"<nil>",
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001@v2",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//kopexa.com/frameworks/nist",
+		"//catalog.kopexa.com/frameworks/iso27001",
+	}
+
+	SortKRNs(ks)
+	for i, k := range ks {
+		got := "<nil>"
+		if k != nil {
+			got = k.String()
+		}
+		if got != want[i] {
+			t.Errorf("position %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestKRN_EqualsIgnoreVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"different versions", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v2", true},
+		{"versioned and unversioned", "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1", "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1", true},
+		{"identical", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", true},
+		{"different ID", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27002@v1", false},
+		{"different service", "//catalog.kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", false},
+		{"different depth", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.EqualsIgnoreVersion(b); got != tt.want {
+				t.Errorf("EqualsIgnoreVersion(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := a.WithoutVersion().Equals(b.WithoutVersion()); got != tt.want {
+				t.Errorf("WithoutVersion().Equals disagrees for %s, %s", tt.a, tt.b)
+			}
+		})
+	}
+
+	t.Run("different domain", func(t *testing.T) {
+		a := MustParse("//kopexa.com/frameworks/iso27001")
+		b, _ := ParseWithOptions("//kopexa.dev/frameworks/iso27001", WithDomain("kopexa.dev"))
+		if a.EqualsIgnoreVersion(b) {
+			t.Error("expected false for different base domains")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/iso27001").EqualsIgnoreVersion(nil) {
+			t.Error("expected false for nil")
+		}
+	})
+
+	t.Run("no allocations", func(t *testing.T) {
+		a := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
+		b := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1@v2")
+		if allocs := testing.AllocsPerRun(100, func() { a.EqualsIgnoreVersion(b) }); allocs != 0 {
+			t.Errorf("expected no allocations, got %v", allocs)
+		}
+	})
+}
+
+func TestKRN_SameResource(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"different services and versions", "//catalog.kopexa.com/frameworks/iso27001@v1", "//isms.kopexa.com/frameworks/iso27001@v2", true},
+		{"with and without service", "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1", "//kopexa.com/frameworks/iso27001/controls/a-5-1", true},
+		{"different ID", "//catalog.kopexa.com/frameworks/iso27001", "//catalog.kopexa.com/frameworks/iso27002", false},
+		{"different collection", "//kopexa.com/frameworks/iso27001", "//kopexa.com/standards/iso27001", false},
+		{"different depth", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.SameResource(b); got != tt.want {
+				t.Errorf("SameResource(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("different domain", func(t *testing.T) {
+		dev, _ := ParseWithOptions("//kopexa.dev/frameworks/iso27001", WithDomain("kopexa.dev"))
+		if !MustParse("//kopexa.com/frameworks/iso27001").SameResource(dev) {
+			t.Error("expected the same resource across domains")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/iso27001").SameResource(nil) {
+			t.Error("expected false for nil")
+		}
+	})
+}
+
+func TestKRN_EqualsFold(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", true},
+		{"ID case", "//kopexa.com/frameworks/ISO27001/controls/A-5-1", "//kopexa.com/frameworks/iso27001/controls/a-5-1", true},
+		{"ID case with service and version", "//catalog.kopexa.com/frameworks/Iso27001@v1", "//catalog.kopexa.com/frameworks/iSO27001@v1", true},
+		{"different ID", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27002", false},
+		{"collection case", "//kopexa.com/Frameworks/iso27001", "//kopexa.com/frameworks/iso27001", false},
+		{"different version", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@V1", false},
+		{"different service", "//catalog.kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", false},
+		{"different depth", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.EqualsFold(b); got != tt.want {
+				t.Errorf("EqualsFold(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.EqualsFold(a); got != tt.want {
+				t.Errorf("EqualsFold(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("default Equals unchanged", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/ISO27001").Equals(MustParse("//kopexa.com/frameworks/iso27001")) {
+			t.Error("Equals must stay case-sensitive")
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		eu, _ := ParseWithOptions("//kopexa.com/frameworks/ISO27001?region=eu", WithQuery())
+		euFold, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+		us, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=us", WithQuery())
+		if !eu.EqualsFold(euFold) {
+			t.Error("expected KRNs with the same query to be equal")
+		}
+		if eu.EqualsFold(us) || eu.EqualsFold(MustParse("//kopexa.com/frameworks/iso27001")) {
+			t.Error("expected KRNs with different queries to differ")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/iso27001").EqualsFold(nil) {
+			t.Error("expected false for nil")
+		}
+	})
+}
+
+func TestKRN_SameShape(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"different IDs", "//kopexa.com/tenants/acme/workspaces/main", "//kopexa.com/tenants/globex/workspaces/dev", true},
+		{"different service names", "//catalog.kopexa.com/frameworks/iso27001", "//isms.kopexa.com/frameworks/nist", true},
+		{"different version values", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/nist@latest", true},
+		{"service presence differs", "//catalog.kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", false},
+		{"version presence differs", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001", false},
+		{"collections differ", "//kopexa.com/frameworks/iso27001", "//kopexa.com/policies/iso27001", false},
+		{"depth differs", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a-5-1", false},
+		{"collection order differs", "//kopexa.com/tenants/a/workspaces/b", "//kopexa.com/workspaces/b/tenants/a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.SameShape(b); got != tt.want {
+				t.Errorf("SameShape(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.SameShape(a); got != tt.want {
+				t.Errorf("SameShape(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/iso27001").SameShape(nil) {
+			t.Error("expected SameShape(nil) to be false")
+		}
+	})
+}
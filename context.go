@@ -0,0 +1,22 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import "context"
+
+// contextKey is the unexported type for context keys defined in this package,
+// preventing collisions with keys from other packages.
+type contextKey struct{}
+
+// WithKRN returns a copy of ctx that carries k as the current resource.
+func WithKRN(ctx context.Context, k *KRN) context.Context {
+	return context.WithValue(ctx, contextKey{}, k)
+}
+
+// FromContext returns the KRN stored in ctx by WithKRN.
+// The boolean is false if ctx carries no KRN or a nil KRN.
+func FromContext(ctx context.Context) (*KRN, bool) {
+	k, ok := ctx.Value(contextKey{}).(*KRN)
+	return k, ok && k != nil
+}
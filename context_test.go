@@ -0,0 +1,49 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		k := MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main")
+		ctx := WithKRN(context.Background(), k)
+
+		got, ok := FromContext(ctx)
+		if !ok {
+			t.Fatal("expected KRN in context")
+		}
+		if got != k {
+			t.Errorf("FromContext() = %v, want %v", got, k)
+		}
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		got, ok := FromContext(context.Background())
+		if ok || got != nil {
+			t.Errorf("FromContext() = (%v, %v), want (nil, false)", got, ok)
+		}
+	})
+
+	t.Run("nil KRN", func(t *testing.T) {
+		got, ok := FromContext(WithKRN(context.Background(), nil))
+		if ok || got != nil {
+			t.Errorf("FromContext() = (%v, %v), want (nil, false)", got, ok)
+		}
+	})
+
+	t.Run("inner value shadows outer", func(t *testing.T) {
+		outer := MustParse("//kopexa.com/tenants/acme")
+		inner := MustParse("//kopexa.com/tenants/acme/workspaces/main")
+		ctx := WithKRN(WithKRN(context.Background(), outer), inner)
+
+		got, _ := FromContext(ctx)
+		if got != inner {
+			t.Errorf("FromContext() = %v, want %v", got, inner)
+		}
+	})
+}
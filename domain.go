@@ -0,0 +1,86 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// domains is the registry of additional base domains accepted by the decoders.
+var (
+	domainsMu sync.RWMutex
+	domains   = map[string]struct{}{}
+)
+
+// RegisterDomain adds domain to the base domains accepted when decoding KRNs
+// from their encodings: UnmarshalJSON, UnmarshalText, UnmarshalYAML, Scan,
+// FromURL, ParseLogToken and absolute references in ResolveRelative. Register
+// every domain passed to WithDomain so that its KRNs can be read back:
+//
+//	krn.RegisterDomain("kopexa.dev")
+//
+// Parse itself still only accepts Domain. Returns ErrInvalidDomain for an
+// invalid domain, for Domain itself and its subdomains, and for a domain that
+// overlaps one already registered (one is a subdomain of the other, e.g.
+// "kopexa.dev" and "eu.kopexa.dev"), so every host maps to a single base
+// domain. Registering a domain again is a no-op. The registry is global;
+// register domains during program initialization.
+func RegisterDomain(domain string) error {
+	if !isValidDomain(domain) {
+		return fmt.Errorf("%w: invalid base domain %q", ErrInvalidDomain, domain)
+	}
+
+	domainsMu.Lock()
+	defer domainsMu.Unlock()
+	if _, ok := domains[domain]; ok {
+		return nil
+	}
+	for d := range domains {
+		if domainsOverlap(domain, d) {
+			return fmt.Errorf("%w: base domain %q overlaps registered domain %q", ErrInvalidDomain, domain, d)
+		}
+	}
+	domains[domain] = struct{}{}
+	return nil
+}
+
+// domainsOverlap reports whether a and b are equal or one is a subdomain of
+// the other.
+func domainsOverlap(a, b string) bool {
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// parseKnownDomain parses s like Parse, but with the longest base domain of
+// its host among Domain, extra (if not empty) and the registered domains. A
+// query (see WithQuery) is accepted, so every encoding of a KRN can be decoded.
+func parseKnownDomain(s, extra string) (*KRN, error) {
+	body, ok := strings.CutPrefix(s, "//")
+	if !ok {
+		return Parse(s)
+	}
+	host, _, _ := strings.Cut(body, "/")
+
+	domain := ""
+	consider := func(d string) {
+		if len(d) > len(domain) && (host == d || strings.HasSuffix(host, "."+d)) {
+			domain = d
+		}
+	}
+	consider(Domain)
+	consider(extra)
+
+	domainsMu.RLock()
+	for d := range domains {
+		consider(d)
+	}
+	domainsMu.RUnlock()
+
+	if domain == "" {
+		// Report the error against the default domain.
+		domain = Domain
+	}
+	return parseKRN(s, parseOptions{domain: domain, allowQuery: true})
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"testing"
+)
+
+// restoreDomains restores the domain registry after the test.
+func restoreDomains(t *testing.T) {
+	t.Helper()
+	domainsMu.RLock()
+	saved := maps.Clone(domains)
+	domainsMu.RUnlock()
+	t.Cleanup(func() {
+		domainsMu.Lock()
+		domains = saved
+		domainsMu.Unlock()
+	})
+}
+
+func TestRegisterDomain(t *testing.T) {
+	restoreDomains(t)
+
+	for _, domain := range []string{"", "Kopexa.dev", "kopexa..dev", ".kopexa.dev", Domain, "eu.kopexa.com", "com"} {
+		if err := RegisterDomain(domain); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("RegisterDomain(%q): expected ErrInvalidDomain, got %v", domain, err)
+		}
+	}
+	if err := RegisterDomain("kopexa.dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RegisterDomain("kopexa.dev"); err != nil {
+		t.Errorf("registering again: unexpected error: %v", err)
+	}
+
+	// Overlapping domains would make hosts ambiguous.
+	for _, domain := range []string{"eu.kopexa.dev", "dev"} {
+		if err := RegisterDomain(domain); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("RegisterDomain(%q): expected ErrInvalidDomain, got %v", domain, err)
+		}
+	}
+	if err := RegisterDomain("kopexa.io"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecoders_RegisteredDomain(t *testing.T) {
+	restoreDomains(t)
+
+	k, err := ParseWithOptions("//isms.kopexa.dev/tenants/acme@v1", WithDomain("kopexa.dev"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := json.Marshal(k)
+
+	var decoded KRN
+	if err := json.Unmarshal(data, &decoded); !errors.Is(err, ErrInvalidDomain) {
+		t.Fatalf("expected ErrInvalidDomain before registration, got %v", err)
+	}
+
+	if err := RegisterDomain("kopexa.dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoders := map[string]func() (*KRN, error){
+		"JSON": func() (*KRN, error) {
+			var got KRN
+			return &got, json.Unmarshal(data, &got)
+		},
+		"Text": func() (*KRN, error) {
+			var got KRN
+			return &got, got.UnmarshalText([]byte(k.String()))
+		},
+		"YAML": func() (*KRN, error) {
+			var got KRN
+			return &got, got.UnmarshalYAML(func(v any) error {
+				s := k.String()
+				*(v.(**string)) = &s
+				return nil
+			})
+		},
+		"Scan": func() (*KRN, error) {
+			var got KRN
+			return &got, got.Scan(k.String())
+		},
+		"URL": func() (*KRN, error) {
+			return FromURL(k.URL())
+		},
+		"LogToken": func() (*KRN, error) {
+			return ParseLogToken(k.LogToken())
+		},
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			got, err := decode()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equals(k) || got.baseDomain() != "kopexa.dev" || got.Service() != "isms" {
+				t.Errorf("decoded %s, want %s", got, k)
+			}
+		})
+	}
+}
+
+func TestDecoders_LongestDomain(t *testing.T) {
+	restoreDomains(t)
+
+	if err := RegisterDomain("kopexa.dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The domain of the base of ResolveRelative may overlap a registered one.
+	base, err := ParseWithOptions("//eu.kopexa.dev/tenants/acme", WithDomain("eu.kopexa.dev"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolved, err := ResolveRelative("//isms.eu.kopexa.dev/tenants/acme", base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.baseDomain() != "eu.kopexa.dev" || resolved.Service() != "isms" {
+		t.Errorf("got domain %q, service %q", resolved.baseDomain(), resolved.Service())
+	}
+
+	var got KRN
+
+	// The default domain is still accepted, and unknown domains are not.
+	if err := got.UnmarshalText([]byte("//kopexa.com/tenants/acme")); err != nil || got.baseDomain() != Domain {
+		t.Errorf("default domain: %v, %s", err, &got)
+	}
+	if err := got.UnmarshalText([]byte("//example.com/tenants/acme")); !errors.Is(err, ErrInvalidDomain) {
+		t.Errorf("expected ErrInvalidDomain, got %v", err)
+	}
+	if err := got.UnmarshalText([]byte("kopexa.dev/tenants/acme")); !errors.Is(err, ErrInvalidKRN) {
+		t.Errorf("expected ErrInvalidKRN, got %v", err)
+	}
+}
@@ -0,0 +1,498 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// URLScheme is the scheme used by KRN.URL.
+const URLScheme = "krn"
+
+// urlVersionParam is the query parameter carrying the version in URL form.
+const urlVersionParam = "version"
+
+// urlQueryParam is the query parameter carrying the KRN query (see WithQuery)
+// in URL form.
+const urlQueryParam = "query"
+
+// LogToken returns the KRN as a single token without "/" or "@", suitable for
+// log labels and Prometheus label values.
+//
+// The encoding drops the leading "//" and then, character by character:
+//
+//	"_" -> "__"
+//	"." -> "_."
+//	"/" -> "."
+//	"@" -> "_v"
+//
+// For example //kopexa.com/frameworks/iso27001@v1 becomes
+// kopexa_.com.frameworks.iso27001_vv1. Every "_" in the output starts a
+// two-character escape, so the encoding is reversible and distinct KRNs always
+// produce distinct tokens. Use ParseLogToken to decode.
+func (k *KRN) LogToken() string {
+	s := k.String()[len("//"):]
+
+	var sb strings.Builder
+	sb.Grow(len(s) + len(s)/4)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '_':
+			sb.WriteString("__")
+		case '.':
+			sb.WriteString("_.")
+		case '/':
+			sb.WriteByte('.')
+		case '@':
+			sb.WriteString("_v")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// ParseLogToken decodes a token produced by LogToken and parses the result like
+// Parse, also accepting queries and registered domains (see RegisterDomain).
+// Returns ErrInvalidKRN for malformed escapes and any error from Parse.
+func ParseLogToken(token string) (*KRN, error) {
+	if token == "" {
+		return nil, ErrEmptyKRN
+	}
+
+	var sb strings.Builder
+	sb.Grow(len("//") + len(token))
+	sb.WriteString("//")
+	for i := 0; i < len(token); i++ {
+		switch c := token[i]; c {
+		case '.':
+			sb.WriteByte('/')
+		case '_':
+			if i+1 == len(token) {
+				return nil, fmt.Errorf("%w: log token ends with incomplete escape", ErrInvalidKRN)
+			}
+			i++
+			switch token[i] {
+			case '_':
+				sb.WriteByte('_')
+			case '.':
+				sb.WriteByte('.')
+			case 'v':
+				sb.WriteByte('@')
+			default:
+				return nil, fmt.Errorf("%w: invalid escape _%c in log token", ErrInvalidKRN, token[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return parseKnownDomain(sb.String(), "")
+}
+
+// ARN-like format constants.
+const (
+	arnPrefix    = "krn"
+	arnPartition = "kopexa" // partition of KRNs in the default Domain
+)
+
+// ARNLike returns the KRN in a colon-delimited form similar to AWS ARNs, for
+// systems that cannot consume the // form:
+//
+//	krn:{partition}:{service}:{path}[:{version}]
+//
+// The partition is "kopexa" for the default Domain and the base domain for
+// KRNs parsed with WithDomain, which rejects "kopexa" as a base domain so the
+// two cannot collide. The service is empty for KRNs without one. For
+// example //catalog.kopexa.com/frameworks/iso27001@v2 becomes
+// krn:kopexa:catalog:frameworks/iso27001:v2 and //kopexa.com/frameworks/iso27001
+// becomes krn:kopexa::frameworks/iso27001. The query, if any, is dropped.
+// Use FromARNLike to convert back; the native KRN form remains canonical.
+func (k *KRN) ARNLike() string {
+	partition := arnPartition
+	if k.domain != "" {
+		partition = k.domain
+	}
+
+	s := arnPrefix + ":" + partition + ":" + k.service + ":" + k.Path()
+	if k.version != "" {
+		s += ":" + k.version
+	}
+	return s
+}
+
+// FromARNLike parses a string produced by ARNLike. Returns ErrEmptyKRN for
+// empty input, ErrInvalidKRN if the prefix or the number of fields is wrong,
+// and otherwise the errors of ParseWithOptions for the equivalent KRN.
+func FromARNLike(s string) (*KRN, error) {
+	if s == "" {
+		return nil, ErrEmptyKRN
+	}
+
+	fields := strings.SplitN(s, ":", 5)
+	if len(fields) < 4 || fields[0] != arnPrefix {
+		return nil, fmt.Errorf("%w: ARN-like form must be %s:{partition}:{service}:{path}[:{version}]", ErrInvalidKRN, arnPrefix)
+	}
+	partition, service, path := fields[1], fields[2], fields[3]
+
+	domain := partition
+	if partition == arnPartition {
+		domain = Domain
+	}
+
+	var sb strings.Builder
+	sb.WriteString("//")
+	if service != "" {
+		sb.WriteString(service)
+		sb.WriteString(".")
+	}
+	sb.WriteString(domain)
+	sb.WriteString("/")
+	sb.WriteString(path)
+	if len(fields) == 5 {
+		sb.WriteString("@")
+		sb.WriteString(fields[4])
+	}
+	return ParseWithOptions(sb.String(), WithDomain(domain))
+}
+
+// Markers of EncodeResourceID. Resource IDs starting with encodedIDPrefix are
+// reserved for encoded identifiers.
+const (
+	encodedIDPrefix  = "x--"
+	resourceIDEscape = '_'
+)
+
+// EncodeResourceID reversibly encodes an arbitrary identifier, such as an email
+// address or a path, as a resource ID. An identifier that already is a valid
+// resource ID, and does not start with the reserved prefix "x--", is returned
+// unchanged. Any other identifier is written as "x--" followed by the
+// identifier with every byte other than an ASCII letter, digit, "-" or "."
+// escaped as "_" plus two uppercase hex digits (so "@" becomes "_40", "/"
+// becomes "_2F" and "_" itself becomes "_5F"). A trailing "-" or "." is
+// escaped as well.
+//
+// For example, "jane@example.com" becomes "x--jane_40example.com" and "a_5_1"
+// stays "a_5_1". Unlike SafeResourceID the encoding is reversible with
+// DecodeResourceID. The result is a valid resource ID unless s is empty or the
+// result exceeds MaxResourceIDLength bytes.
+func EncodeResourceID(s string) string {
+	if IsValidResourceID(s) && !strings.HasPrefix(s, encodedIDPrefix) {
+		return s
+	}
+
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	sb.Grow(len(encodedIDPrefix) + len(s))
+	sb.WriteString(encodedIDPrefix)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAlphanumeric(c) || (c == '-' || c == '.') && i != len(s)-1 {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte(resourceIDEscape)
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0x0F])
+	}
+	return sb.String()
+}
+
+// DecodeResourceID reverses EncodeResourceID. IDs without the "x--" prefix
+// were not encoded and are returned unchanged, so any resource ID can be
+// decoded. IDs with the prefix must be exactly what EncodeResourceID produces;
+// otherwise ErrInvalidResourceID is returned. This keeps the encoding
+// one-to-one: every identifier has a single encoded form.
+func DecodeResourceID(s string) (string, error) {
+	body, ok := strings.CutPrefix(s, encodedIDPrefix)
+	if !ok {
+		return s, nil
+	}
+
+	b := make([]byte, 0, len(body))
+	for i := 0; i < len(body); i++ {
+		if body[i] != resourceIDEscape {
+			b = append(b, body[i])
+			continue
+		}
+		if i+2 >= len(body) {
+			return "", fmt.Errorf("%w: incomplete escape in %s", ErrInvalidResourceID, s)
+		}
+		hi, ok1 := unhex(body[i+1])
+		lo, ok2 := unhex(body[i+2])
+		if !ok1 || !ok2 {
+			return "", fmt.Errorf("%w: invalid escape %s in %s", ErrInvalidResourceID, body[i:i+3], s)
+		}
+		b = append(b, hi<<4|lo)
+		i += 2
+	}
+
+	decoded := string(b)
+	if EncodeResourceID(decoded) != s {
+		return "", fmt.Errorf("%w: %s is not a canonical encoding", ErrInvalidResourceID, s)
+	}
+	return decoded, nil
+}
+
+// unhex returns the value of the uppercase hex digit c.
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// MarshalJSON implements json.Marshaler. A KRN is encoded as its canonical
+// string form; a nil KRN is encoded as null.
+func (k *KRN) MarshalJSON() ([]byte, error) {
+	if k == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(k.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a JSON string and
+// parses it like Parse, also accepting queries and registered domains (see
+// RegisterDomain), so the usual sentinel errors (ErrEmptyKRN, ErrInvalidKRN,
+// ...) can be checked with errors.Is. A JSON null is a no-op, which leaves *KRN
+// fields nil. Other JSON types return ErrInvalidKRN.
+func (k *KRN) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: KRN must be a JSON string: %w", ErrInvalidKRN, err)
+	}
+
+	parsed, err := parseKnownDomain(s, "")
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler and returns the canonical
+// string form. A nil KRN returns ErrEmptyKRN.
+func (k *KRN) MarshalText() ([]byte, error) {
+	if k == nil {
+		return nil, ErrEmptyKRN
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and parses text like Parse,
+// also accepting queries and registered domains (see RegisterDomain). Empty
+// text returns ErrEmptyKRN. On error k is left unchanged.
+func (k *KRN) UnmarshalText(text []byte) error {
+	parsed, err := parseKnownDomain(string(text), "")
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface of gopkg.in/yaml.v3
+// (and v2) without importing it. A KRN is encoded as a string scalar holding
+// its canonical form; a nil KRN is encoded as null.
+func (k *KRN) MarshalYAML() (any, error) {
+	if k == nil {
+		return nil, nil
+	}
+	return k.String(), nil
+}
+
+// UnmarshalYAML implements the function-based yaml.Unmarshaler interface, which
+// both gopkg.in/yaml.v2 and v3 support. The scalar is parsed like Parse, also
+// accepting queries and registered domains (see RegisterDomain), so the usual
+// sentinel errors can be checked with errors.Is. A null value is a no-op, which
+// leaves *KRN fields nil. Non-string values return ErrInvalidKRN.
+func (k *KRN) UnmarshalYAML(unmarshal func(any) error) error {
+	var s *string
+	if err := unmarshal(&s); err != nil {
+		return fmt.Errorf("%w: KRN must be a YAML string: %w", ErrInvalidKRN, err)
+	}
+	if s == nil {
+		return nil
+	}
+
+	parsed, err := parseKnownDomain(*s, "")
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// Value implements driver.Valuer and stores a KRN as its canonical string.
+// It has a value receiver, so database/sql stores a nil *KRN as NULL and
+// sql.Null[KRN] can store non-pointer KRNs. The zero KRN returns ErrEmptyKRN.
+func (k KRN) Value() (driver.Value, error) { //nolint:gocritic // hugeParam: driver.Valuer must be implemented by KRN values for sql.Null[KRN].
+	if len(k.segments) == 0 {
+		return nil, ErrEmptyKRN
+	}
+	return k.String(), nil
+}
+
+// Scan implements sql.Scanner for string and []byte columns. Values are parsed
+// like Parse, also accepting queries and registered domains (see
+// RegisterDomain).
+//
+// Stored values that do not parse return an error wrapping both ErrInvalidKRN
+// and the error from Parse, so corrupted data can be detected with
+// errors.Is(err, ErrInvalidKRN). SQL NULL returns an error wrapping ErrEmptyKRN
+// and leaves k unchanged; scan nullable columns into a *KRN or sql.Null[KRN]
+// instead.
+func (k *KRN) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		return fmt.Errorf("%w: cannot scan NULL into KRN", ErrEmptyKRN)
+	default:
+		return fmt.Errorf("%w: cannot scan %T into KRN", ErrInvalidKRN, src)
+	}
+
+	parsed, err := parseKnownDomain(s, "")
+	if err != nil {
+		return fmt.Errorf("%w: stored value %q: %w", ErrInvalidKRN, s, err)
+	}
+	*k = *parsed
+	return nil
+}
+
+// URL returns the KRN as a URL with the "krn" scheme, the full domain as host,
+// the resource path as path, the version, if any, as the "version" query
+// parameter and the KRN query, if any, as the "query" parameter, e.g.
+// krn://catalog.kopexa.com/frameworks/iso27001?query=region%3Deu&version=v1.
+func (k *KRN) URL() *url.URL {
+	return k.URLWithScheme(URLScheme)
+}
+
+// URLWithScheme is like URL but uses the given scheme, e.g. "https" to map KRNs
+// onto REST endpoints.
+func (k *KRN) URLWithScheme(scheme string) *url.URL {
+	u := &url.URL{
+		Scheme: scheme,
+		Host:   k.FullDomain(),
+		Path:   "/" + k.Path(),
+	}
+	params := url.Values{}
+	if k.version != "" {
+		params.Set(urlVersionParam, k.version)
+	}
+	if k.query != "" {
+		params.Set(urlQueryParam, k.query)
+	}
+	u.RawQuery = params.Encode()
+	return u
+}
+
+// FromURL converts a URL produced by URL or URLWithScheme back into a KRN. The
+// scheme is not checked; the host, path and "version" and "query" parameters
+// are parsed like Parse, also accepting queries and domains registered with
+// RegisterDomain, and return the same errors. Other query parameters are
+// ignored. A nil URL returns ErrEmptyKRN.
+func FromURL(u *url.URL) (*KRN, error) {
+	if u == nil {
+		return nil, ErrEmptyKRN
+	}
+
+	s := "//" + u.Host + u.Path
+	params := u.Query()
+	if version := params.Get(urlVersionParam); version != "" {
+		s += "@" + version
+	}
+	if query := params.Get(urlQueryParam); query != "" {
+		s += "?" + query
+	}
+	return parseKnownDomain(s, "")
+}
+
+// FilePath returns a relative filesystem path for the KRN, e.g. to cache
+// resources on disk: the full domain, then one directory per collection and
+// resource ID, then "@" plus the version and "+" plus the query (see WithQuery)
+// if present, joined with the OS path separator:
+//
+//	//catalog.kopexa.com/frameworks/ISO27001@v1 -> catalog.kopexa.com/frameworks/_49_53_4f27001/@v1
+//
+// Every element is escaped so the path is safe on Unix and Windows: only
+// lowercase letters, digits, "-", "." and "_" are written, and any other byte,
+// including uppercase letters and "_" itself, becomes "_" plus two lowercase
+// hex digits. A leading or trailing "." and the first byte of Windows device
+// names (con, nul, com1, ...) are escaped as well, so no element is "." or ".."
+// or reserved. The escaping is injective, so different KRNs never share a path,
+// even on case-insensitive filesystems. FilePath is not meant to be parsed back.
+func (k *KRN) FilePath() string {
+	elems := make([]string, 0, 2+2*len(k.segments))
+	elems = append(elems, filePathElement(k.FullDomain()))
+	for _, seg := range k.segments {
+		elems = append(elems, filePathElement(seg.Collection), filePathElement(seg.ResourceID))
+	}
+	if k.version != "" {
+		elems = append(elems, "@"+filePathElement(k.version))
+	}
+	if k.query != "" {
+		elems = append(elems, "+"+filePathElement(k.query))
+	}
+	return filepath.Join(elems...)
+}
+
+// filePathElement escapes s for use as a single file name. See FilePath.
+func filePathElement(s string) string {
+	const hex = "0123456789abcdef"
+
+	reserved := isWindowsDeviceName(s)
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		edge := i == 0 || i == len(s)-1
+		safe := isLowerAlphanumeric(c) || c == '-' || (c == '.' && !edge)
+		if safe && !(i == 0 && reserved) {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('_')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0x0F])
+	}
+	return sb.String()
+}
+
+// isLowerAlphanumeric reports whether c is a lowercase ASCII letter or digit.
+func isLowerAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9'
+}
+
+// isWindowsDeviceName reports whether s, ignoring case and any extension, is a
+// reserved Windows device name such as "con" or "lpt1".
+func isWindowsDeviceName(s string) bool {
+	stem, _, _ := strings.Cut(strings.ToLower(s), ".")
+	switch stem {
+	case "con", "prn", "aux", "nul":
+		return true
+	}
+	if len(stem) == 4 && (strings.HasPrefix(stem, "com") || strings.HasPrefix(stem, "lpt")) {
+		return '0' <= stem[3] && stem[3] <= '9'
+	}
+	return false
+}
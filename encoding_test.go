@@ -0,0 +1,753 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKRN_LogToken(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"//kopexa.com/frameworks/iso27001", "kopexa_.com.frameworks.iso27001"},
+		{"//kopexa.com/frameworks/iso27001@v1", "kopexa_.com.frameworks.iso27001_vv1"},
+		{"//catalog.kopexa.com/frameworks/iso27001/controls/5.1.1@v1.2.3", "catalog_.kopexa_.com.frameworks.iso27001.controls.5_.1_.1_vv1_.2_.3"},
+		{"//kopexa.com/frameworks/iso_27001", "kopexa_.com.frameworks.iso__27001"},
+		{"//isms.kopexa.com/tenants/acme-corp@latest", "isms_.kopexa_.com.tenants.acme-corp_vlatest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			token := MustParse(tt.input).LogToken()
+			if token != tt.want {
+				t.Errorf("LogToken() = %q, want %q", token, tt.want)
+			}
+			if strings.ContainsAny(token, "/@") {
+				t.Errorf("LogToken() = %q contains / or @", token)
+			}
+
+			k, err := ParseLogToken(token)
+			if err != nil {
+				t.Fatalf("ParseLogToken(%q) error: %v", token, err)
+			}
+			if k.String() != tt.input {
+				t.Errorf("ParseLogToken(%q) = %q, want %q", token, k.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestKRN_LogToken_Distinct(t *testing.T) {
+	// Pairs that would collide under a naive "/" -> "." replacement.
+	inputs := []string{
+		"//kopexa.com/frameworks/a.b/controls/c",
+		"//kopexa.com/frameworks/a/b.controls/c",
+		"//kopexa.com/frameworks/a_v1",
+		"//kopexa.com/frameworks/a@v1",
+		"//kopexa.com/frameworks/a__b",
+		"//kopexa.com/frameworks/a_b",
+	}
+
+	seen := make(map[string]string)
+	for _, input := range inputs {
+		k, err := Parse(input)
+		if err != nil {
+			continue
+		}
+		token := k.LogToken()
+		if prev, ok := seen[token]; ok {
+			t.Errorf("%s and %s share log token %q", prev, input, token)
+		}
+		seen[token] = input
+	}
+}
+
+func TestParseLogToken_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"empty", "", ErrEmptyKRN},
+		{"dangling escape", "kopexa_.com.frameworks.iso27001_", ErrInvalidKRN},
+		{"unknown escape", "kopexa_.com.frameworks.iso_x27001", ErrInvalidKRN},
+		{"wrong domain", "example_.com.frameworks.iso27001", ErrInvalidDomain},
+		{"invalid version", "kopexa_.com.frameworks.iso27001_v-1", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseLogToken(tt.token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKRN_ARNLike(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"//catalog.kopexa.com/frameworks/iso27001@v2", "krn:kopexa:catalog:frameworks/iso27001:v2"},
+		{"//kopexa.com/frameworks/iso27001", "krn:kopexa::frameworks/iso27001"},
+		{"//isms.kopexa.com/tenants/acme/workspaces/main", "krn:kopexa:isms:tenants/acme/workspaces/main"},
+		{"//kopexa.com/frameworks/iso27001/controls/a.5.1@2022-01", "krn:kopexa::frameworks/iso27001/controls/a.5.1:2022-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k := MustParse(tt.input)
+			got := k.ARNLike()
+			if got != tt.want {
+				t.Errorf("ARNLike() = %q, want %q", got, tt.want)
+			}
+
+			back, err := FromARNLike(got)
+			if err != nil {
+				t.Fatalf("FromARNLike(%q) error: %v", got, err)
+			}
+			if !back.Equals(k) {
+				t.Errorf("round trip = %s, want %s", back, k)
+			}
+		})
+	}
+
+	t.Run("custom domain", func(t *testing.T) {
+		k, err := ParseWithOptions("//isms.kopexa.dev/tenants/acme@v1", WithDomain("kopexa.dev"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := k.ARNLike(); got != "krn:kopexa.dev:isms:tenants/acme:v1" {
+			t.Errorf("ARNLike() = %q", got)
+		}
+		back, err := FromARNLike(k.ARNLike())
+		if err != nil || !back.Equals(k) {
+			t.Errorf("round trip = %v, %v", back, err)
+		}
+	})
+
+	t.Run("reserved partition domain", func(t *testing.T) {
+		// A base domain "kopexa" would export the partition of the default Domain.
+		if _, err := ParseWithOptions("//isms.kopexa/tenants/acme", WithDomain("kopexa")); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("expected ErrInvalidDomain, got %v", err)
+		}
+		if err := RegisterDomain("kopexa"); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("expected ErrInvalidDomain, got %v", err)
+		}
+		k, err := FromARNLike("krn:kopexa:isms:tenants/acme")
+		if err != nil || k.FullDomain() != "isms.kopexa.com" {
+			t.Errorf("FromARNLike() = %v, %v", k, err)
+		}
+	})
+
+	t.Run("default domain partition", func(t *testing.T) {
+		k, err := FromARNLike("krn:kopexa.com:catalog:frameworks/iso27001")
+		if err != nil || !k.EqualsString("//catalog.kopexa.com/frameworks/iso27001") {
+			t.Errorf("FromARNLike() = %v, %v", k, err)
+		}
+	})
+}
+
+func TestFromARNLike_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"empty", "", ErrEmptyKRN},
+		{"wrong prefix", "arn:kopexa:catalog:frameworks/iso27001", ErrInvalidKRN},
+		{"too few fields", "krn:kopexa:frameworks/iso27001", ErrInvalidKRN},
+		{"native form", "//kopexa.com/frameworks/iso27001", ErrInvalidKRN},
+		{"invalid service", "krn:kopexa:Catalog:frameworks/iso27001", ErrInvalidService},
+		{"invalid partition", "krn:not valid:catalog:frameworks/iso27001", ErrInvalidDomain},
+		{"odd path", "krn:kopexa:catalog:frameworks", ErrInvalidKRN},
+		{"empty version", "krn:kopexa:catalog:frameworks/iso27001:", ErrInvalidVersion},
+		{"extra field", "krn:kopexa:catalog:frameworks/iso27001:v1:x", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromARNLike(tt.input); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEncodeResourceID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		valid bool
+	}{
+		{"iso27001", "iso27001", true},
+		{"a.5-1", "a.5-1", true},
+		{"a_5_1", "a_5_1", true},
+		{"a_40", "a_40", true},
+		{"jane@example.com", "x--jane_40example.com", true},
+		{"org/repo", "x--org_2Frepo", true},
+		{"a_5 1", "x--a_5F5_201", true},
+		{"with space", "x--with_20space", true},
+		{"trailing.", "x--trailing_2E", true},
+		{"caf\u00e9", "x--caf_C3_A9", true},
+		{"-leading", "x---leading", true},
+		{"@handle", "x--_40handle", true},
+		{"_x", "x--_5Fx", true},
+		{"x--reserved", "x--x--reserved", true},
+		{"", "x--", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := EncodeResourceID(tt.input)
+			if got != tt.want {
+				t.Errorf("EncodeResourceID(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if IsValidResourceID(got) != tt.valid {
+				t.Errorf("IsValidResourceID(%q) = %v, want %v", got, !tt.valid, tt.valid)
+			}
+			decoded, err := DecodeResourceID(got)
+			if err != nil || decoded != tt.input {
+				t.Errorf("DecodeResourceID(%q) = (%q, %v), want %q", got, decoded, err, tt.input)
+			}
+		})
+	}
+}
+
+func TestDecodeResourceID_Errors(t *testing.T) {
+	for _, input := range []string{
+		"x--a_4",                  // incomplete escape
+		"x--a_",                   // incomplete escape
+		"x--a_zz1",                // not hex
+		"x--jane_40example_2ecom", // lowercase hex
+		"x--a_2Eb",                // "." needs no escape
+		"x--iso27001",             // plain ID needs no prefix
+		"x--trailing.",            // trailing "." must be escaped
+	} {
+		if _, err := DecodeResourceID(input); !errors.Is(err, ErrInvalidResourceID) {
+			t.Errorf("DecodeResourceID(%q): expected ErrInvalidResourceID, got %v", input, err)
+		}
+	}
+
+	// IDs that were never encoded are returned unchanged, even if they contain "_".
+	for _, input := range []string{"a_40", "a_5_1", "iso27001"} {
+		if got, err := DecodeResourceID(input); err != nil || got != input {
+			t.Errorf("DecodeResourceID(%q) = (%q, %v), want unchanged", input, got, err)
+		}
+	}
+}
+
+func TestKRN_JSON(t *testing.T) {
+	type resource struct {
+		ID     *KRN `json:"id"`
+		Parent *KRN `json:"parent"`
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		inputs := []string{
+			"//kopexa.com/frameworks/iso27001",
+			"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.3",
+			"//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev_1@latest",
+		}
+		for _, input := range inputs {
+			k := MustParse(input)
+			data, err := json.Marshal(resource{ID: k})
+			if err != nil {
+				t.Fatalf("Marshal error: %v", err)
+			}
+			want := `{"id":"` + input + `","parent":null}`
+			if string(data) != want {
+				t.Errorf("Marshal = %s, want %s", data, want)
+			}
+
+			var got resource
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal error: %v", err)
+			}
+			if !got.ID.Equals(k) || got.ID.Service() != k.Service() || got.ID.Version() != k.Version() {
+				t.Errorf("round trip = %s, want %s", got.ID, k)
+			}
+			if got.Parent != nil {
+				t.Errorf("expected nil parent, got %s", got.Parent)
+			}
+		}
+	})
+
+	t.Run("marshal nil", func(t *testing.T) {
+		var k *KRN
+		data, err := k.MarshalJSON()
+		if err != nil || string(data) != "null" {
+			t.Errorf("MarshalJSON() = (%s, %v), want null", data, err)
+		}
+	})
+
+	t.Run("unmarshal null is a no-op", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if err := k.UnmarshalJSON([]byte("null")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if k.String() != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("KRN modified: %s", k)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			data    string
+			wantErr error
+		}{
+			{"empty string", `{"id":""}`, ErrEmptyKRN},
+			{"invalid format", `{"id":"not-a-krn"}`, ErrInvalidKRN},
+			{"invalid domain", `{"id":"//example.com/frameworks/x"}`, ErrInvalidDomain},
+			{"invalid version", `{"id":"//kopexa.com/frameworks/x@-bad"}`, ErrInvalidVersion},
+			{"number", `{"id":42}`, ErrInvalidKRN},
+			{"object", `{"id":{}}`, ErrInvalidKRN},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var r resource
+				if err := json.Unmarshal([]byte(tt.data), &r); !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+}
+
+func TestKRN_Value(t *testing.T) {
+	k := MustParse("//catalog.kopexa.com/frameworks/iso27001@v1")
+	v, err := k.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "//catalog.kopexa.com/frameworks/iso27001@v1" {
+		t.Errorf("Value() = %v", v)
+	}
+
+	t.Run("nil pointer is NULL", func(t *testing.T) {
+		var nilKRN *KRN
+		v, err := driver.DefaultParameterConverter.ConvertValue(nilKRN)
+		if err != nil || v != nil {
+			t.Errorf("ConvertValue(nil) = (%v, %v), want (nil, nil)", v, err)
+		}
+	})
+
+	t.Run("zero KRN", func(t *testing.T) {
+		if _, err := (KRN{}).Value(); !errors.Is(err, ErrEmptyKRN) {
+			t.Errorf("expected ErrEmptyKRN, got %v", err)
+		}
+	})
+}
+
+func TestKRN_Scan(t *testing.T) {
+	const want = "//isms.kopexa.com/tenants/acme-corp/workspaces/main@v2"
+
+	for _, src := range []any{want, []byte(want)} {
+		var k KRN
+		if err := k.Scan(src); err != nil {
+			t.Fatalf("Scan(%T) error: %v", src, err)
+		}
+		if k.String() != want {
+			t.Errorf("Scan(%T) = %s, want %s", src, k.String(), want)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			src     any
+			wantErr []error
+		}{
+			{"NULL", nil, []error{ErrEmptyKRN}},
+			{"unsupported type", 42, []error{ErrInvalidKRN}},
+			{"empty string", "", []error{ErrInvalidKRN, ErrEmptyKRN}},
+			{"corrupted", "//kopexa.com/frameworks", []error{ErrInvalidKRN}},
+			{"invalid domain", []byte("//example.com/frameworks/x"), []error{ErrInvalidKRN, ErrInvalidDomain}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				k := MustParse("//kopexa.com/frameworks/iso27001")
+				err := k.Scan(tt.src)
+				for _, wantErr := range tt.wantErr {
+					if !errors.Is(err, wantErr) {
+						t.Errorf("expected error %v, got %v", wantErr, err)
+					}
+				}
+				if k.String() != "//kopexa.com/frameworks/iso27001" {
+					t.Errorf("KRN modified on error: %s", k)
+				}
+			})
+		}
+	})
+
+	t.Run("sql.Null", func(t *testing.T) {
+		var n sql.Null[KRN]
+		if err := n.Scan(nil); err != nil || n.Valid {
+			t.Errorf("Scan(nil) = (%v, valid=%v), want (nil, false)", err, n.Valid)
+		}
+		if err := n.Scan(want); err != nil || !n.Valid || n.V.String() != want {
+			t.Errorf("Scan(%q) = (%v, valid=%v, %s)", want, err, n.Valid, n.V.String())
+		}
+		if v, err := n.Value(); err != nil || v != want {
+			t.Errorf("Value() = (%v, %v), want %q", v, err, want)
+		}
+	})
+}
+
+func TestKRN_Text(t *testing.T) {
+	var (
+		_ encoding.TextMarshaler   = (*KRN)(nil)
+		_ encoding.TextUnmarshaler = (*KRN)(nil)
+	)
+
+	const input = "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.3"
+	text, err := MustParse(input).MarshalText()
+	if err != nil || string(text) != input {
+		t.Fatalf("MarshalText() = (%s, %v), want %s", text, err, input)
+	}
+
+	var k KRN
+	if err := k.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText() error: %v", err)
+	}
+	if k.String() != input {
+		t.Errorf("UnmarshalText() = %s, want %s", k.String(), input)
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var nilKRN *KRN
+		if _, err := nilKRN.MarshalText(); !errors.Is(err, ErrEmptyKRN) {
+			t.Errorf("expected ErrEmptyKRN, got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			text    string
+			wantErr error
+		}{
+			{"", ErrEmptyKRN},
+			{"kopexa.com/frameworks/x", ErrInvalidKRN},
+			{"//kopexa.com/frameworks/x@-bad", ErrInvalidVersion},
+		}
+		for _, tt := range tests {
+			k := MustParse("//kopexa.com/frameworks/iso27001")
+			if err := k.UnmarshalText([]byte(tt.text)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalText(%q): expected error %v, got %v", tt.text, tt.wantErr, err)
+			}
+			if k.String() != "//kopexa.com/frameworks/iso27001" {
+				t.Errorf("UnmarshalText(%q) modified the KRN: %s", tt.text, k.String())
+			}
+		}
+	})
+}
+
+// yamlUnmarshal mimics the decode callback yaml.v2/v3 pass to UnmarshalYAML,
+// using JSON as a stand-in for the YAML scalar.
+func yamlUnmarshal(scalar string) func(any) error {
+	return func(v any) error {
+		return json.Unmarshal([]byte(scalar), v)
+	}
+}
+
+func TestKRN_YAML(t *testing.T) {
+	const input = "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.3"
+
+	v, err := MustParse(input).MarshalYAML()
+	if err != nil || v != input {
+		t.Fatalf("MarshalYAML() = (%v, %v), want %s", v, err, input)
+	}
+
+	var nilKRN *KRN
+	if v, err := nilKRN.MarshalYAML(); v != nil || err != nil {
+		t.Errorf("nil MarshalYAML() = (%v, %v), want (nil, nil)", v, err)
+	}
+
+	var k KRN
+	if err := k.UnmarshalYAML(yamlUnmarshal(`"` + input + `"`)); err != nil {
+		t.Fatalf("UnmarshalYAML() error: %v", err)
+	}
+	if k.String() != input {
+		t.Errorf("UnmarshalYAML() = %s, want %s", k.String(), input)
+	}
+
+	t.Run("null", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if err := k.UnmarshalYAML(yamlUnmarshal("null")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if k.String() != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("null modified the KRN: %s", k.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			scalar  string
+			wantErr error
+		}{
+			{`""`, ErrEmptyKRN},
+			{`"kopexa.com/frameworks/x"`, ErrInvalidKRN},
+			{`"//kopexa.com/frameworks/x@-bad"`, ErrInvalidVersion},
+			{`42`, ErrInvalidKRN},
+		}
+		for _, tt := range tests {
+			var k KRN
+			if err := k.UnmarshalYAML(yamlUnmarshal(tt.scalar)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalYAML(%s): expected error %v, got %v", tt.scalar, tt.wantErr, err)
+			}
+		}
+	})
+}
+
+func TestKRN_URL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"//kopexa.com/frameworks/iso27001", "krn://kopexa.com/frameworks/iso27001"},
+		{"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.3", "krn://catalog.kopexa.com/frameworks/iso27001/controls/a-5-1?version=v1.2.3"},
+		{"//isms.kopexa.com/tenants/acme/evidences/ev_1@latest", "krn://isms.kopexa.com/tenants/acme/evidences/ev_1?version=latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k := MustParse(tt.input)
+			u := k.URL()
+			if u.String() != tt.want {
+				t.Errorf("URL() = %q, want %q", u.String(), tt.want)
+			}
+
+			parsed, err := url.Parse(u.String())
+			if err != nil {
+				t.Fatalf("url.Parse error: %v", err)
+			}
+			back, err := FromURL(parsed)
+			if err != nil {
+				t.Fatalf("FromURL error: %v", err)
+			}
+			if !back.Equals(k) {
+				t.Errorf("FromURL() = %s, want %s", back, k)
+			}
+		})
+	}
+
+	t.Run("custom scheme", func(t *testing.T) {
+		k := MustParse("//catalog.kopexa.com/frameworks/iso27001@v1")
+		u := k.URLWithScheme("https")
+		if u.String() != "https://catalog.kopexa.com/frameworks/iso27001?version=v1" {
+			t.Errorf("URLWithScheme() = %q", u.String())
+		}
+		back, err := FromURL(u)
+		if err != nil || !back.Equals(k) {
+			t.Errorf("FromURL() = (%v, %v)", back, err)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		k, _ := ParseWithOptions("//catalog.kopexa.com/frameworks/iso27001@v1?region=eu&tag=a", WithQuery())
+		u := k.URL()
+		if u.String() != "krn://catalog.kopexa.com/frameworks/iso27001?query=region%3Deu%26tag%3Da&version=v1" {
+			t.Errorf("URL() = %q", u.String())
+		}
+		parsed, err := url.Parse(u.String())
+		if err != nil {
+			t.Fatalf("url.Parse error: %v", err)
+		}
+		back, err := FromURL(parsed)
+		if err != nil || back.String() != k.String() {
+			t.Errorf("FromURL() = (%v, %v), want %s", back, err, k)
+		}
+	})
+}
+
+func TestFromURL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{"wrong host", "krn://example.com/frameworks/iso27001", ErrInvalidDomain},
+		{"port", "https://kopexa.com:443/frameworks/iso27001", ErrInvalidDomain},
+		{"odd path", "krn://kopexa.com/frameworks", ErrInvalidKRN},
+		{"invalid version", "krn://kopexa.com/frameworks/iso27001?version=-bad", ErrInvalidVersion},
+		{"invalid resource ID", "krn://kopexa.com/frameworks/-bad", ErrInvalidResourceID},
+		{"invalid query", "krn://kopexa.com/frameworks/iso27001?query=%25zz", ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("url.Parse error: %v", err)
+			}
+			if _, err := FromURL(u); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if _, err := FromURL(nil); !errors.Is(err, ErrEmptyKRN) {
+			t.Errorf("expected ErrEmptyKRN, got %v", err)
+		}
+	})
+}
+
+func TestKRN_FilePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"//kopexa.com/frameworks/iso27001", "kopexa.com/frameworks/iso27001"},
+		{"//catalog.kopexa.com/frameworks/ISO27001@v1", "catalog.kopexa.com/frameworks/_49_53_4f27001/@v1"},
+		{"//kopexa.com/frameworks/iso27001/controls/a.5_1@2022-01", "kopexa.com/frameworks/iso27001/controls/a.5_5f1/@2022-01"},
+		{"//kopexa.com/devices/con", "kopexa.com/devices/_63on"},
+		{"//kopexa.com/devices/COM1.txt", "kopexa.com/devices/_43_4f_4d1.txt"},
+		{"//kopexa.com/devices/com", "kopexa.com/devices/com"},
+		{"//kopexa.com/../x", "kopexa.com/_2e_2e/x"},
+		{"//kopexa.com/a:b/c", "kopexa.com/a_3ab/c"},
+		{"//kopexa.com/tenants/acme@v1?region=eu", "kopexa.com/tenants/acme/@v1/+region_3deu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k, err := ParseWithOptions(tt.input, WithQuery())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filepath.ToSlash(k.FilePath()); got != tt.want {
+				t.Errorf("FilePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no collisions", func(t *testing.T) {
+		inputs := []string{
+			"//kopexa.com/tenants/acme",
+			"//kopexa.com/tenants/Acme",
+			"//kopexa.com/tenants/ACME",
+			"//kopexa.com/tenants/a_63me",
+			"//kopexa.com/tenants/a_41cme",
+			"//kopexa.com/tenants/acme@v1",
+			"//kopexa.com/tenants/acme@V1",
+			"//isms.kopexa.com/tenants/acme",
+			"//kopexa.com/Tenants/acme",
+			"//kopexa.com/con/x",
+			"//kopexa.com/_63on/x",
+			"//kopexa.com/tenants/acme/workspaces/main",
+			"//kopexa.com/tenants/acme/workspaces/main@v1",
+			"//kopexa.com/tenants/acme?region=eu",
+			"//kopexa.com/tenants/acme?region=us",
+			"//kopexa.com/tenants/acme@v1?region=eu",
+		}
+		staging, err := ParseWithOptions("//kopexa.dev/tenants/acme", WithDomain("kopexa.dev"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := map[string]string{strings.ToLower(staging.FilePath()): staging.String()}
+		for _, s := range inputs {
+			// Compare case-insensitively, as on Windows and macOS.
+			k, err := ParseWithOptions(s, WithQuery())
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := strings.ToLower(k.FilePath())
+			if other, ok := seen[p]; ok {
+				t.Errorf("%s and %s share path %s", s, other, p)
+			}
+			seen[p] = s
+		}
+	})
+
+	t.Run("safe elements", func(t *testing.T) {
+		p := MustParse(`//kopexa.com/../x/.?*<>|\:"/y`).FilePath()
+		for _, elem := range strings.Split(filepath.ToSlash(p), "/") {
+			if elem == "." || elem == ".." || strings.ContainsAny(elem, `\:*?"<>|`) {
+				t.Errorf("unsafe path element %q in %q", elem, p)
+			}
+		}
+	})
+}
+
+func TestDecoders_Query(t *testing.T) {
+	k, err := ParseWithOptions("//isms.kopexa.com/tenants/acme@v1?region=eu&tag=a", WithQuery())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoders := map[string]func() (*KRN, error){
+		"JSON": func() (*KRN, error) {
+			data, err := json.Marshal(k)
+			if err != nil {
+				return nil, err
+			}
+			var got KRN
+			return &got, json.Unmarshal(data, &got)
+		},
+		"Text": func() (*KRN, error) {
+			text, err := k.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			var got KRN
+			return &got, got.UnmarshalText(text)
+		},
+		"YAML": func() (*KRN, error) {
+			v, err := k.MarshalYAML()
+			if err != nil {
+				return nil, err
+			}
+			var got KRN
+			return &got, got.UnmarshalYAML(func(out any) error {
+				s := v.(string)
+				*(out.(**string)) = &s
+				return nil
+			})
+		},
+		"Scan": func() (*KRN, error) {
+			v, err := k.Value()
+			if err != nil {
+				return nil, err
+			}
+			var got KRN
+			return &got, got.Scan(v)
+		},
+		"Binary": func() (*KRN, error) {
+			data, err := k.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			var got KRN
+			return &got, got.UnmarshalBinary(data)
+		},
+		"LogToken": func() (*KRN, error) {
+			return ParseLogToken(k.LogToken())
+		},
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			got, err := decode()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equals(k) || got.String() != k.String() {
+				t.Errorf("decoded %s, want %s", got, k)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// redactedID replaces sensitive resource IDs in RedactedString.
+const redactedID = "***"
+
+// redactedCollections holds the collections masked by RedactedString.
+var (
+	redactedCollectionsMu sync.RWMutex
+	redactedCollections   = []string{string(ResourceTypeTenants)}
+)
+
+// Format implements fmt.Formatter:
+//
+//	%s, %v  canonical string, e.g. //catalog.kopexa.com/frameworks/iso27001@v1
+//	%q      canonical string, double-quoted
+//	%+v     verbose breakdown, e.g.
+//	        {Domain:kopexa.com Service:catalog Segments:[frameworks/iso27001] Version:v1}
+//	%#v     Go syntax, see GoString
+//
+// Width and precision apply to %s, %v and %q as for strings. A nil KRN prints
+// as <nil>, or (*krn.KRN)(nil) with %#v.
+func (k *KRN) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		_, _ = fmt.Fprint(f, k.GoString())
+	case k == nil:
+		_, _ = fmt.Fprint(f, "<nil>")
+	case verb == 'v' && f.Flag('+'):
+		_, _ = fmt.Fprint(f, k.verbose())
+	case verb == 's' || verb == 'v' || verb == 'q':
+		_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), k.String())
+	default:
+		_, _ = fmt.Fprintf(f, "%%!%c(*krn.KRN=%s)", verb, k.String())
+	}
+}
+
+// GoString implements fmt.GoStringer and returns a Go expression that
+// reproduces the KRN, e.g. krn.MustParse("//kopexa.com/frameworks/iso27001").
+func (k *KRN) GoString() string {
+	if k == nil {
+		return "(*krn.KRN)(nil)"
+	}
+	var opts []string
+	if k.domain != "" {
+		opts = append(opts, fmt.Sprintf("krn.WithDomain(%q)", k.domain))
+	}
+	if k.query != "" {
+		opts = append(opts, "krn.WithQuery()")
+	}
+	if len(opts) == 0 {
+		return fmt.Sprintf("krn.MustParse(%q)", k.String())
+	}
+	return fmt.Sprintf("func() *krn.KRN { k, _ := krn.ParseWithOptions(%q, %s); return k }()", k.String(), strings.Join(opts, ", "))
+}
+
+// verbose returns the %+v rendering of the KRN.
+func (k *KRN) verbose() string {
+	segments := make([]string, len(k.segments))
+	for i, seg := range k.segments {
+		segments[i] = seg.String()
+	}
+	var query string
+	if k.query != "" {
+		query = " Query:" + k.query
+	}
+	return fmt.Sprintf("{Domain:%s Service:%s Segments:[%s] Version:%s%s}",
+		k.baseDomain(), k.service, strings.Join(segments, " "), k.version, query)
+}
+
+// RedactedString returns the canonical string with the resource IDs of
+// sensitive collections replaced by "***", for logs that must not contain
+// tenant identifiers, e.g. //isms.kopexa.com/tenants/***/workspaces/main.
+// The service, the other segments, the version and the query are kept.
+//
+// By default only "tenants" is redacted; see SetRedactedCollections.
+func (k *KRN) RedactedString() string {
+	redactedCollectionsMu.RLock()
+	defer redactedCollectionsMu.RUnlock()
+
+	// The masked KRN is only rendered, never returned, so the invalid "***"
+	// resource IDs do not escape.
+	masked := &KRN{
+		domain:   k.domain,
+		service:  k.service,
+		segments: make([]Segment, len(k.segments)),
+		version:  k.version,
+		query:    k.query,
+	}
+	for i, seg := range k.segments {
+		if slices.Contains(redactedCollections, seg.Collection) {
+			seg.ResourceID = redactedID
+		}
+		masked.segments[i] = seg
+	}
+	return masked.String()
+}
+
+// SetRedactedCollections replaces the collections whose resource IDs
+// RedactedString masks. Calling it without arguments disables redaction.
+// It is safe for concurrent use; configure it during program initialization.
+func SetRedactedCollections(collections ...string) {
+	redactedCollectionsMu.Lock()
+	defer redactedCollectionsMu.Unlock()
+	redactedCollections = slices.Clone(collections)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKRN_Format(t *testing.T) {
+	k := MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
+	plain := MustParse("//kopexa.com/frameworks/iso27001")
+	dev, _ := ParseWithOptions("//kopexa.dev/frameworks/iso27001", WithDomain("kopexa.dev"))
+	withQuery, _ := ParseWithOptions("//kopexa.dev/frameworks/iso27001?region=eu", WithDomain("kopexa.dev"), WithQuery())
+	var nilKRN *KRN
+
+	tests := []struct {
+		name   string
+		format string
+		arg    any
+		want   string
+	}{
+		{"v", "%v", k, "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1"},
+		{"s", "%s", k, "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1"},
+		{"q", "%q", plain, `"//kopexa.com/frameworks/iso27001"`},
+		{"width", "[%-34s]", plain, "[//kopexa.com/frameworks/iso27001  ]"},
+		{"precision", "%.12s", plain, "//kopexa.com"},
+		{"verbose", "%+v", k, "{Domain:kopexa.com Service:catalog Segments:[frameworks/iso27001 controls/a-5-1] Version:v1}"},
+		{"verbose without service and version", "%+v", plain, "{Domain:kopexa.com Service: Segments:[frameworks/iso27001] Version:}"},
+		{"go syntax", "%#v", plain, `krn.MustParse("//kopexa.com/frameworks/iso27001")`},
+		{"go syntax with domain", "%#v", dev, `func() *krn.KRN { k, _ := krn.ParseWithOptions("//kopexa.dev/frameworks/iso27001", krn.WithDomain("kopexa.dev")); return k }()`},
+		{"verbose with query", "%+v", withQuery, "{Domain:kopexa.dev Service: Segments:[frameworks/iso27001] Version: Query:region=eu}"},
+		{"go syntax with query", "%#v", withQuery, `func() *krn.KRN { k, _ := krn.ParseWithOptions("//kopexa.dev/frameworks/iso27001?region=eu", krn.WithDomain("kopexa.dev"), krn.WithQuery()); return k }()`},
+		{"bad verb", "%d", plain, "%!d(*krn.KRN=//kopexa.com/frameworks/iso27001)"},
+		{"nil", "%v", nilKRN, "<nil>"},
+		{"nil verbose", "%+v", nilKRN, "<nil>"},
+		{"nil go syntax", "%#v", nilKRN, "(*krn.KRN)(nil)"},
+		{"in struct", "%v", struct{ ID *KRN }{plain}, "{//kopexa.com/frameworks/iso27001}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprintf(tt.format, tt.arg); got != tt.want {
+				t.Errorf("Sprintf(%q) = %s, want %s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKRN_RedactedString(t *testing.T) {
+	t.Cleanup(func() { SetRedactedCollections(string(ResourceTypeTenants)) })
+
+	k := MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v1")
+
+	if got, want := k.RedactedString(), "//isms.kopexa.com/tenants/***/workspaces/main/evidences/ev-1@v1"; got != want {
+		t.Errorf("RedactedString() = %s, want %s", got, want)
+	}
+	if k.String() != "//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v1" {
+		t.Errorf("RedactedString modified the KRN: %s", k)
+	}
+	if got := MustParse("//kopexa.com/frameworks/iso27001").RedactedString(); got != "//kopexa.com/frameworks/iso27001" {
+		t.Errorf("RedactedString() without sensitive collections = %s", got)
+	}
+
+	SetRedactedCollections("tenants", "workspaces")
+	if got, want := k.RedactedString(), "//isms.kopexa.com/tenants/***/workspaces/***/evidences/ev-1@v1"; got != want {
+		t.Errorf("RedactedString() = %s, want %s", got, want)
+	}
+
+	SetRedactedCollections()
+	if got := k.RedactedString(); got != k.String() {
+		t.Errorf("expected no redaction, got %s", got)
+	}
+}
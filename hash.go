@@ -0,0 +1,44 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"hash/fnv"
+)
+
+// ShortIDLength is the length of the identifiers returned by ShortID.
+const ShortIDLength = 24
+
+// shortIDEncoding is lowercase base32 (RFC 4648 alphabet) without padding.
+var shortIDEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Hash returns a 64-bit FNV-1a hash of the canonical string of the KRN, for
+// sharding, consistent-hashing and cache partitioning.
+//
+// The hash is deterministic: it is stable across runs, processes and versions
+// of this package, and KRNs for which Equals is true always have equal hashes.
+// Like UUID, it covers the version, so KRNs that differ only in version hash
+// differently. It is not a cryptographic hash.
+func (k *KRN) Hash() uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(k.String())) // hash.Hash.Write never returns an error
+	return h.Sum64()
+}
+
+// ShortID returns a compact, URL-safe identifier for the KRN, e.g. for URLs or
+// as a foreign key in systems that cannot store the full KRN: the first 120
+// bits of the SHA-256 hash of the canonical string, encoded as ShortIDLength
+// lowercase base32 characters (a-z, 2-7).
+//
+// Like Hash, the result is stable across runs, processes and versions of this
+// package, KRNs for which Equals is true always have the same ShortID, and the
+// version is covered. Collisions are practically impossible (about 2^60 KRNs
+// are needed for an even chance of one), but ShortID cannot be decoded; keep a
+// mapping to the full KRN.
+func (k *KRN) ShortID() string {
+	sum := sha256.Sum256([]byte(k.String()))
+	return shortIDEncoding.EncodeToString(sum[:15])
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestKRN_Hash(t *testing.T) {
+	a := MustParse("//kopexa.com/frameworks/iso27001")
+	b := MustParse("//kopexa.com/frameworks/iso27001")
+
+	if a.Hash() != b.Hash() {
+		t.Error("expected equal KRNs to have equal hashes")
+	}
+
+	// Pinned value: the hash must not change between releases.
+	if got, want := a.Hash(), uint64(0x562b404e588b419a); got != want {
+		t.Errorf("Hash() = %#x, want %#x", got, want)
+	}
+
+	others := []string{
+		"//kopexa.com/frameworks/iso27001@v1",
+		"//catalog.kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27002",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+	}
+	for _, s := range others {
+		if MustParse(s).Hash() == a.Hash() {
+			t.Errorf("expected %s to hash differently from %s", s, a)
+		}
+	}
+}
+
+func TestKRN_ShortID(t *testing.T) {
+	a := MustParse("//kopexa.com/frameworks/iso27001")
+	b := MustParse("//kopexa.com/frameworks/iso27001")
+
+	if a.ShortID() != b.ShortID() {
+		t.Error("expected equal KRNs to have equal short IDs")
+	}
+
+	// Pinned value: the short ID must not change between releases.
+	if got, want := a.ShortID(), "r4aluk6lotzbov6fzkcse2ux"; got != want {
+		t.Errorf("ShortID() = %q, want %q", got, want)
+	}
+
+	seen := make(map[string]string)
+	for i := range 10000 {
+		k := MustParse(fmt.Sprintf("//kopexa.com/tenants/t-%d/workspaces/main@v%d", i%100, i/100))
+		id := k.ShortID()
+		if len(id) != ShortIDLength {
+			t.Fatalf("ShortID() = %q has length %d, want %d", id, len(id), ShortIDLength)
+		}
+		if url.PathEscape(id) != id {
+			t.Fatalf("ShortID() = %q is not URL-safe", id)
+		}
+		if other, ok := seen[id]; ok {
+			t.Fatalf("%s and %s share short ID %q", k, other, id)
+		}
+		seen[id] = k.String()
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// Breadcrumb is one navigation level of a KRN: a display label and the KRN it links to.
+type Breadcrumb struct {
+	Label string
+	KRN   *KRN
+}
+
+// Breadcrumbs returns one breadcrumb per level of the KRN, from the root resource
+// to the KRN itself. Each label is the resource ID of that level.
+// All breadcrumbs keep the service; only the last one keeps the version, since
+// versions apply to the leaf resource.
+func (k *KRN) Breadcrumbs() []Breadcrumb {
+	prefixes := k.BreadcrumbKRNs()
+	crumbs := make([]Breadcrumb, len(prefixes))
+	for i, prefix := range prefixes {
+		crumbs[i] = Breadcrumb{
+			Label: k.segments[i].ResourceID,
+			KRN:   prefix,
+		}
+	}
+	return crumbs
+}
+
+// BreadcrumbKRNs returns the KRN of every level from the root resource down to
+// k itself, e.g. for breadcrumb APIs where the client renders each level with
+// its BasenameCollection and Basename. Like Breadcrumbs, every KRN keeps the
+// service and only the last one keeps the version. Each KRN is a new copy.
+func (k *KRN) BreadcrumbKRNs() []*KRN {
+	prefixes := make([]*KRN, len(k.segments))
+	for i := range k.segments {
+		prefixes[i] = k.prefix(i + 1)
+	}
+	if n := len(prefixes); n > 0 {
+		prefixes[n-1].version = k.version
+	}
+	return prefixes
+}
+
+// TrimToDepth returns a new KRN with only the first n segments, e.g. to roll
+// evidences up to their control. The service is kept and the version is dropped,
+// as with Parent. Returns ErrInvalidKRN if n is less than 1 or greater than Depth().
+func (k *KRN) TrimToDepth(n int) (*KRN, error) {
+	if n < 1 || n > len(k.segments) {
+		return nil, fmt.Errorf("%w: depth %d out of range [1, %d]", ErrInvalidKRN, n, len(k.segments))
+	}
+	return k.prefix(n), nil
+}
+
+// prefix returns a new unversioned KRN with the domain, the service and the
+// first n segments of k.
+func (k *KRN) prefix(n int) *KRN {
+	newSegments := make([]Segment, n)
+	copy(newSegments, k.segments[:n])
+
+	return &KRN{
+		domain:   k.domain,
+		service:  k.service,
+		segments: newSegments,
+	}
+}
+
+// Ancestors returns an iterator over the ancestors of k, starting with the
+// parent and ending with the root resource. It never yields k itself or nil,
+// and yields nothing for a root resource. Like Parent, every ancestor is
+// unversioned and a new KRN.
+func (k *KRN) Ancestors() iter.Seq[*KRN] {
+	return func(yield func(*KRN) bool) {
+		for n := len(k.segments) - 1; n >= 1; n-- {
+			if !yield(k.prefix(n)) {
+				return
+			}
+		}
+	}
+}
+
+// Prefixes returns an iterator over every prefix of k from the root resource
+// down to k itself, e.g. for registering each node of a resource tree. It is
+// the reverse of Ancestors plus k. Every prefix is unversioned and a new KRN.
+func (k *KRN) Prefixes() iter.Seq[*KRN] {
+	return func(yield func(*KRN) bool) {
+		for n := 1; n <= len(k.segments); n++ {
+			if !yield(k.prefix(n)) {
+				return
+			}
+		}
+	}
+}
+
+// MinimalLabels returns, for each KRN, the shortest trailing label that tells it
+// apart from every other KRN in the list. The result maps each KRN's canonical
+// string to its label.
+//
+// Labels are the trailing resource IDs joined with "/": for a list of controls
+// under //kopexa.com/frameworks/iso27001 the labels are just the control IDs, and
+// controls with the same ID in different frameworks get "iso27001/a-5-1"-style
+// labels. KRNs that cannot be told apart by resource IDs alone (e.g. they differ
+// only in service, collection or version) are labeled with their canonical string.
+// Exact duplicates and nil entries are ignored.
+func MinimalLabels(krns []*KRN) map[string]string {
+	distinct := make(map[string]*KRN, len(krns))
+	maxDepth := 0
+	for _, k := range krns {
+		if k == nil {
+			continue
+		}
+		distinct[k.String()] = k
+		maxDepth = max(maxDepth, len(k.segments))
+	}
+
+	// counts[n-1][label] is the number of KRNs whose last n resource IDs form label.
+	counts := make([]map[string]int, maxDepth)
+	for n := range counts {
+		counts[n] = make(map[string]int)
+	}
+	for _, k := range distinct {
+		for n := 1; n <= len(k.segments); n++ {
+			counts[n-1][suffixLabel(k, n)]++
+		}
+	}
+
+	labels := make(map[string]string, len(distinct))
+	for s, k := range distinct {
+		labels[s] = s
+		for n := 1; n <= len(k.segments); n++ {
+			if label := suffixLabel(k, n); counts[n-1][label] == 1 {
+				labels[s] = label
+				break
+			}
+		}
+	}
+	return labels
+}
+
+// suffixLabel joins the last n resource IDs of k with "/".
+func suffixLabel(k *KRN, n int) string {
+	ids := make([]string, 0, n)
+	for _, seg := range k.segments[len(k.segments)-n:] {
+		ids = append(ids, seg.ResourceID)
+	}
+	return strings.Join(ids, "/")
+}
+
+// hasSegmentPrefix reports whether k has the same host as base and starts
+// with all of base's segments. Versions are ignored.
+func hasSegmentPrefix(k, base *KRN) bool {
+	if !sameHost(k, base) || len(k.segments) < len(base.segments) {
+		return false
+	}
+	return sameSegments(k.segments[:len(base.segments)], base.segments)
+}
+
+// IsAncestorOf reports whether k is a strict ancestor of other: both have the
+// same service and other starts with all of k's segments plus at least one more.
+// Versions are ignored. A KRN is not its own ancestor, and a nil other returns false.
+func (k *KRN) IsAncestorOf(other *KRN) bool {
+	return other != nil && len(other.segments) > len(k.segments) && hasSegmentPrefix(other, k)
+}
+
+// IsDescendantOf reports whether k is a strict descendant of other.
+// It is equivalent to other.IsAncestorOf(k) and returns false if other is nil.
+func (k *KRN) IsDescendantOf(other *KRN) bool {
+	return other != nil && other.IsAncestorOf(k)
+}
+
+// CommonAncestor returns the deepest KRN shared by a and b: a new, unversioned
+// KRN with their longest common leading sequence of segments. Segments match
+// only if both the collection and the resource ID are equal, so the common
+// ancestor of //kopexa.com/frameworks/iso27001/controls/a and
+// //kopexa.com/frameworks/iso27001/controls/b is //kopexa.com/frameworks/iso27001.
+//
+// The prefix is inclusive: if a is an ancestor of b (or they denote the same
+// resource), the result is a without its version.
+// Returns nil if either KRN is nil, the services differ, or the first segments differ.
+func CommonAncestor(a, b *KRN) *KRN {
+	if a == nil || b == nil || !sameHost(a, b) {
+		return nil
+	}
+
+	n := 0
+	for n < len(a.segments) && n < len(b.segments) && a.segments[n] == b.segments[n] {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	newSegments := make([]Segment, n)
+	copy(newSegments, a.segments[:n])
+
+	return &KRN{
+		domain:   a.domain,
+		service:  a.service,
+		segments: newSegments,
+	}
+}
+
+// RelativeTo returns the shortest reference to k from within the scope of base.
+// ResolveRelative(k.RelativeTo(base), base) yields k again, provided that an
+// absolute reference is in the default Domain, the domain of base or a domain
+// registered with RegisterDomain.
+//
+// The reference grammar mirrors URL references:
+//
+//	collection/id[/collection/id][@version]   relative to base; k is a descendant of base
+//	/collection/id[/collection/id][@version]  rooted path; k has the same service as base
+//	//host/collection/id[...][@version]       absolute KRN; any other case
+//
+// The version and query of base are ignored; those of k are always kept.
+func (k *KRN) RelativeTo(base *KRN) string {
+	if base == nil || !sameHost(k, base) {
+		return k.String()
+	}
+
+	var segments []Segment
+	prefix := "/"
+	if base.IsAncestorOf(k) {
+		segments = k.segments[len(base.segments):]
+		prefix = ""
+	} else {
+		segments = k.segments
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for i, seg := range segments {
+		if i > 0 {
+			sb.WriteString("/")
+		}
+		sb.WriteString(seg.String())
+	}
+	if k.version != "" {
+		sb.WriteString("@")
+		sb.WriteString(k.version)
+	}
+	if k.query != "" {
+		sb.WriteString("?")
+		sb.WriteString(k.query)
+	}
+	return sb.String()
+}
+
+// RelativePath returns the collection/id path of k below base, e.g.
+// "controls/a-5-1" for k = //kopexa.com/frameworks/iso27001/controls/a-5-1 and
+// base = //kopexa.com/frameworks/iso27001. It is the inverse of a chain of
+// NewChild calls on base. Versions are ignored.
+//
+// Unlike RelativeTo, which falls back to rooted or absolute references,
+// RelativePath returns ErrInvalidKRN unless base.IsAncestorOf(k).
+func (k *KRN) RelativePath(base *KRN) (string, error) {
+	if base == nil || !base.IsAncestorOf(k) {
+		return "", fmt.Errorf("%w: %v is not an ancestor of %s", ErrInvalidKRN, base, k)
+	}
+
+	parts := make([]string, 0, len(k.segments)-len(base.segments))
+	for _, seg := range k.segments[len(base.segments):] {
+		parts = append(parts, seg.String())
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// ResolveRelative expands a reference produced by RelativeTo against base.
+// See RelativeTo for the grammar. Absolute KRNs are parsed as-is, accepting
+// the default Domain, the domain of base and domains registered with
+// RegisterDomain; other references require a non-nil base and take its base
+// domain and service.
+// Returns the same errors as Parse.
+func ResolveRelative(ref string, base *KRN) (*KRN, error) {
+	switch {
+	case ref == "":
+		return nil, ErrEmptyKRN
+	case strings.HasPrefix(ref, "//"):
+		if base == nil {
+			return parseKnownDomain(ref, "")
+		}
+		return parseKnownDomain(ref, base.baseDomain())
+	case base == nil:
+		return nil, fmt.Errorf("%w: relative reference %s requires a base", ErrInvalidKRN, ref)
+	case strings.HasPrefix(ref, "/"):
+		return parseKRN("//"+base.FullDomain()+ref, parseOptions{domain: base.baseDomain(), allowQuery: true})
+	default:
+		return parseKRN("//"+base.FullDomain()+"/"+base.Path()+"/"+ref, parseOptions{domain: base.baseDomain(), allowQuery: true})
+	}
+}
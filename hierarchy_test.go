@@ -0,0 +1,611 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKRN_Breadcrumbs(t *testing.T) {
+	t.Run("nested with service and version", func(t *testing.T) {
+		k := MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v2")
+		crumbs := k.Breadcrumbs()
+
+		want := []struct {
+			label string
+			krn   string
+		}{
+			{"acme-corp", "//isms.kopexa.com/tenants/acme-corp"},
+			{"main", "//isms.kopexa.com/tenants/acme-corp/workspaces/main"},
+			{"ev-1", "//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v2"},
+		}
+
+		if len(crumbs) != len(want) {
+			t.Fatalf("expected %d breadcrumbs, got %d", len(want), len(crumbs))
+		}
+		for i, w := range want {
+			if crumbs[i].Label != w.label {
+				t.Errorf("crumb %d: label = %q, want %q", i, crumbs[i].Label, w.label)
+			}
+			if crumbs[i].KRN.String() != w.krn {
+				t.Errorf("crumb %d: KRN = %q, want %q", i, crumbs[i].KRN.String(), w.krn)
+			}
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		crumbs := k.Breadcrumbs()
+		if len(crumbs) != 1 {
+			t.Fatalf("expected 1 breadcrumb, got %d", len(crumbs))
+		}
+		if crumbs[0].Label != "iso27001" || !crumbs[0].KRN.Equals(k) {
+			t.Errorf("unexpected breadcrumb: %s %s", crumbs[0].Label, crumbs[0].KRN)
+		}
+		if crumbs[0].KRN == k {
+			t.Error("expected a new KRN, got the receiver")
+		}
+	})
+
+	t.Run("prefixes do not alias the receiver", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+		crumbs := k.Breadcrumbs()
+		crumbs[0].KRN.segments[0].ResourceID = "modified"
+		if k.String() != "//kopexa.com/frameworks/iso27001/controls/a-5-1" {
+			t.Errorf("original modified: %q", k.String())
+		}
+	})
+}
+
+func TestKRN_TrimToDepth(t *testing.T) {
+	k := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2")
+
+	tests := []struct {
+		n       int
+		want    string
+		wantErr bool
+	}{
+		{1, "//isms.kopexa.com/tenants/acme", false},
+		{2, "//isms.kopexa.com/tenants/acme/workspaces/main", false},
+		{3, "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", false},
+		{0, "", true},
+		{-1, "", true},
+		{4, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := k.TrimToDepth(tt.n)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidKRN) {
+				t.Errorf("TrimToDepth(%d): expected ErrInvalidKRN, got %v", tt.n, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("TrimToDepth(%d): unexpected error: %v", tt.n, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("TrimToDepth(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+
+	t.Run("does not alias receiver", func(t *testing.T) {
+		got, _ := k.TrimToDepth(1)
+		got.segments[0].ResourceID = "modified"
+		if k.String() != "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2" {
+			t.Errorf("original modified: %s", k)
+		}
+	})
+}
+
+func TestKRN_Ancestors(t *testing.T) {
+	t.Run("nested", func(t *testing.T) {
+		k := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2")
+		want := []string{
+			"//isms.kopexa.com/tenants/acme/workspaces/main",
+			"//isms.kopexa.com/tenants/acme",
+		}
+
+		var got []string
+		for anc := range k.Ancestors() {
+			got = append(got, anc.String())
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Ancestors() = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("ancestor %d = %s, want %s", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		for anc := range MustParse("//kopexa.com/frameworks/iso27001@v1").Ancestors() {
+			t.Errorf("unexpected ancestor %s", anc)
+		}
+	})
+
+	t.Run("early break", func(t *testing.T) {
+		k := MustParse("//kopexa.com/a/1/b/2/c/3/d/4")
+		count := 0
+		for anc := range k.Ancestors() {
+			count++
+			if anc.Depth() == 3 {
+				break
+			}
+		}
+		if count != 1 {
+			t.Errorf("expected 1 iteration, got %d", count)
+		}
+	})
+
+	t.Run("matches Parent chain", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1/evidences/ev-1")
+		p := k.Parent()
+		for anc := range k.Ancestors() {
+			if !anc.Equals(p) {
+				t.Errorf("ancestor %s, want %s", anc, p)
+			}
+			p = p.Parent()
+		}
+		if p != nil {
+			t.Errorf("expected Parent chain to end, got %s", p)
+		}
+	})
+}
+
+func TestKRN_BreadcrumbKRNs(t *testing.T) {
+	t.Run("nested", func(t *testing.T) {
+		k := MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v2")
+		want := []string{
+			"//catalog.kopexa.com/frameworks/iso27001",
+			"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v2",
+		}
+
+		var got []string
+		for _, p := range k.BreadcrumbKRNs() {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("BreadcrumbKRNs() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		got := MustParse("//kopexa.com/frameworks/iso27001@v1").BreadcrumbKRNs()
+		if len(got) != 1 || got[0].String() != "//kopexa.com/frameworks/iso27001@v1" {
+			t.Errorf("BreadcrumbKRNs() = %v", got)
+		}
+	})
+
+	t.Run("custom domain", func(t *testing.T) {
+		k, err := ParseWithOptions("//isms.example.com/tenants/acme/workspaces/main", WithDomain("example.com"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := k.BreadcrumbKRNs()
+		if len(got) != 2 || got[0].FullDomain() != "isms.example.com" || !got[1].Equals(k) {
+			t.Errorf("BreadcrumbKRNs() = %v", got)
+		}
+	})
+
+	t.Run("independent copies", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+		for _, p := range k.BreadcrumbKRNs() {
+			p.segments[0].ResourceID = "changed"
+		}
+		if k.String() != "//kopexa.com/frameworks/iso27001/controls/a-5-1" {
+			t.Errorf("BreadcrumbKRNs mutated the original: %s", k)
+		}
+	})
+}
+
+func TestKRN_Prefixes(t *testing.T) {
+	t.Run("nested", func(t *testing.T) {
+		k := MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1/evidences/ev-1@v2")
+		want := []string{
+			"//catalog.kopexa.com/frameworks/iso27001",
+			"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1",
+			"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1/evidences/ev-1",
+		}
+
+		var got []string
+		for p := range k.Prefixes() {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Prefixes() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		var got []string
+		for p := range MustParse("//kopexa.com/frameworks/iso27001@v1").Prefixes() {
+			got = append(got, p.String())
+		}
+		if len(got) != 1 || got[0] != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("Prefixes() = %v", got)
+		}
+	})
+
+	t.Run("early break", func(t *testing.T) {
+		count := 0
+		for range MustParse("//kopexa.com/a/1/b/2/c/3").Prefixes() {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("expected 1 iteration, got %d", count)
+		}
+	})
+
+	t.Run("independent copies", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+		for p := range k.Prefixes() {
+			p.segments[0].ResourceID = "changed"
+		}
+		if k.String() != "//kopexa.com/frameworks/iso27001/controls/a-5-1" {
+			t.Errorf("Prefixes mutated the original: %s", k)
+		}
+	})
+}
+
+func TestMinimalLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		want   map[string]string
+	}{
+		{
+			name: "siblings use resource ID",
+			inputs: []string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-2",
+				"//kopexa.com/frameworks/iso27001/controls/a-6-1",
+			},
+			want: map[string]string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1": "a-5-1",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-2": "a-5-2",
+				"//kopexa.com/frameworks/iso27001/controls/a-6-1": "a-6-1",
+			},
+		},
+		{
+			name: "same ID in different parents",
+			inputs: []string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+				"//kopexa.com/frameworks/nist/controls/a-5-1",
+				"//kopexa.com/frameworks/nist/controls/gv-1",
+			},
+			want: map[string]string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1": "iso27001/a-5-1",
+				"//kopexa.com/frameworks/nist/controls/a-5-1":     "nist/a-5-1",
+				"//kopexa.com/frameworks/nist/controls/gv-1":      "gv-1",
+			},
+		},
+		{
+			name: "mixed depths",
+			inputs: []string{
+				"//kopexa.com/frameworks/iso27001",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+			},
+			want: map[string]string{
+				"//kopexa.com/frameworks/iso27001":                "iso27001",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1": "a-5-1",
+			},
+		},
+		{
+			name: "differ only in service or version",
+			inputs: []string{
+				"//kopexa.com/frameworks/iso27001",
+				"//catalog.kopexa.com/frameworks/iso27001",
+				"//catalog.kopexa.com/frameworks/iso27001@v2",
+				"//kopexa.com/frameworks/nist",
+			},
+			want: map[string]string{
+				"//kopexa.com/frameworks/iso27001":            "//kopexa.com/frameworks/iso27001",
+				"//catalog.kopexa.com/frameworks/iso27001":    "//catalog.kopexa.com/frameworks/iso27001",
+				"//catalog.kopexa.com/frameworks/iso27001@v2": "//catalog.kopexa.com/frameworks/iso27001@v2",
+				"//kopexa.com/frameworks/nist":                "nist",
+			},
+		},
+		{
+			name: "exact duplicates",
+			inputs: []string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-2",
+			},
+			want: map[string]string{
+				"//kopexa.com/frameworks/iso27001/controls/a-5-1": "a-5-1",
+				"//kopexa.com/frameworks/iso27001/controls/a-5-2": "a-5-2",
+			},
+		},
+		{
+			name:   "empty",
+			inputs: nil,
+			want:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			krns := make([]*KRN, 0, len(tt.inputs))
+			for _, s := range tt.inputs {
+				krns = append(krns, MustParse(s))
+			}
+
+			got := MinimalLabels(krns)
+			if len(got) != len(tt.want) {
+				t.Fatalf("MinimalLabels() returned %d labels, want %d: %v", len(got), len(tt.want), got)
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Errorf("label for %s = %q, want %q", k, got[k], want)
+				}
+			}
+		})
+	}
+
+	t.Run("nil entries ignored", func(t *testing.T) {
+		got := MinimalLabels([]*KRN{nil, MustParse("//kopexa.com/frameworks/iso27001"), nil})
+		if len(got) != 1 || got["//kopexa.com/frameworks/iso27001"] != "iso27001" {
+			t.Errorf("unexpected labels: %v", got)
+		}
+	})
+}
+
+func TestKRN_IsAncestorOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		ancestor string
+		other    string
+		want     bool
+	}{
+		{"parent", "//kopexa.com/tenants/acme", "//kopexa.com/tenants/acme/workspaces/main", true},
+		{"grandparent", "//isms.kopexa.com/tenants/acme", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", true},
+		{"versions ignored", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001/controls/a-5-1@v2", true},
+		{"identical", "//kopexa.com/tenants/acme", "//kopexa.com/tenants/acme", false},
+		{"same resource other version", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v2", false},
+		{"sibling", "//kopexa.com/tenants/acme", "//kopexa.com/tenants/globex/workspaces/main", false},
+		{"ID prefix only", "//kopexa.com/tenants/acme", "//kopexa.com/tenants/acme-corp/workspaces/main", false},
+		{"different collection", "//kopexa.com/tenants/acme", "//kopexa.com/orgs/acme/workspaces/main", false},
+		{"different service", "//catalog.kopexa.com/tenants/acme", "//isms.kopexa.com/tenants/acme/workspaces/main", false},
+		{"service vs none", "//kopexa.com/tenants/acme", "//isms.kopexa.com/tenants/acme/workspaces/main", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, o := MustParse(tt.ancestor), MustParse(tt.other)
+			if got := a.IsAncestorOf(o); got != tt.want {
+				t.Errorf("IsAncestorOf() = %v, want %v", got, tt.want)
+			}
+			if got := o.IsDescendantOf(a); got != tt.want {
+				t.Errorf("IsDescendantOf() = %v, want %v", got, tt.want)
+			}
+			if tt.want && (o.IsAncestorOf(a) || a.IsDescendantOf(o)) {
+				t.Error("relationship must not be symmetric")
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		k := MustParse("//kopexa.com/tenants/acme")
+		if k.IsAncestorOf(nil) || k.IsDescendantOf(nil) {
+			t.Error("expected false for nil")
+		}
+	})
+}
+
+func TestCommonAncestor(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"siblings", "//kopexa.com/frameworks/iso27001/controls/a", "//kopexa.com/frameworks/iso27001/controls/b", "//kopexa.com/frameworks/iso27001"},
+		{"cousins", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", "//isms.kopexa.com/tenants/acme/workspaces/dev/evidences/ev-1", "//isms.kopexa.com/tenants/acme"},
+		{"ancestor", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a", "//kopexa.com/frameworks/iso27001"},
+		{"same resource drops version", "//kopexa.com/frameworks/iso27001/controls/a@v1", "//kopexa.com/frameworks/iso27001/controls/a@v2", "//kopexa.com/frameworks/iso27001/controls/a"},
+		{"different collection", "//kopexa.com/frameworks/iso27001/controls/a", "//kopexa.com/frameworks/iso27001/policies/a", "//kopexa.com/frameworks/iso27001"},
+		{"different roots", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/nist", ""},
+		{"different services", "//catalog.kopexa.com/frameworks/iso27001/controls/a", "//isms.kopexa.com/frameworks/iso27001/controls/b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			for _, got := range []*KRN{CommonAncestor(a, b), CommonAncestor(b, a)} {
+				if tt.want == "" {
+					if got != nil {
+						t.Errorf("expected nil, got %s", got)
+					}
+					continue
+				}
+				if got == nil || got.String() != tt.want {
+					t.Errorf("CommonAncestor() = %v, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if CommonAncestor(nil, k) != nil || CommonAncestor(k, nil) != nil {
+			t.Error("expected nil")
+		}
+	})
+
+	t.Run("does not alias inputs", func(t *testing.T) {
+		a := MustParse("//kopexa.com/frameworks/iso27001/controls/a")
+		got := CommonAncestor(a, a)
+		got.segments[0].ResourceID = "modified"
+		if a.String() != "//kopexa.com/frameworks/iso27001/controls/a" {
+			t.Errorf("original modified: %s", a)
+		}
+	})
+}
+
+func TestKRN_RelativeTo(t *testing.T) {
+	tests := []struct {
+		name string
+		k    string
+		base string
+		want string
+	}{
+		{"child", "//kopexa.com/frameworks/iso27001/controls/a-5-1", "//kopexa.com/frameworks/iso27001", "controls/a-5-1"},
+		{"grandchild", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", "//isms.kopexa.com/tenants/acme", "workspaces/main/evidences/ev-1"},
+		{"child keeps version", "//kopexa.com/frameworks/iso27001/controls/a-5-1@v2", "//kopexa.com/frameworks/iso27001", "controls/a-5-1@v2"},
+		{"base version ignored", "//kopexa.com/frameworks/iso27001/controls/a-5-1", "//kopexa.com/frameworks/iso27001@v1", "controls/a-5-1"},
+		{"sibling", "//catalog.kopexa.com/frameworks/nist", "//catalog.kopexa.com/frameworks/iso27001", "/frameworks/nist"},
+		{"same resource", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001", "/frameworks/iso27001@v1"},
+		{"ancestor", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a-5-1", "/frameworks/iso27001"},
+		{"different service", "//isms.kopexa.com/tenants/acme", "//catalog.kopexa.com/frameworks/iso27001", "//isms.kopexa.com/tenants/acme"},
+		{"service vs none", "//kopexa.com/frameworks/iso27001/controls/a", "//catalog.kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, base := MustParse(tt.k), MustParse(tt.base)
+			ref := k.RelativeTo(base)
+			if ref != tt.want {
+				t.Errorf("RelativeTo() = %q, want %q", ref, tt.want)
+			}
+
+			resolved, err := ResolveRelative(ref, base)
+			if err != nil {
+				t.Fatalf("ResolveRelative(%q) error: %v", ref, err)
+			}
+			if !resolved.Equals(k) {
+				t.Errorf("ResolveRelative(%q) = %s, want %s", ref, resolved, k)
+			}
+		})
+	}
+
+	t.Run("absolute reference in the base domain", func(t *testing.T) {
+		restoreDomains(t)
+		k, _ := ParseWithOptions("//isms.kopexa.dev/tenants/acme", WithDomain("kopexa.dev"))
+		base, _ := ParseWithOptions("//catalog.kopexa.dev/frameworks/iso27001", WithDomain("kopexa.dev"))
+
+		ref := k.RelativeTo(base)
+		resolved, err := ResolveRelative(ref, base)
+		if err != nil || !resolved.Equals(k) {
+			t.Errorf("ResolveRelative(%q) = (%v, %v), want %s", ref, resolved, err, k)
+		}
+
+		// Other domains need to be registered.
+		other := MustParse("//kopexa.com/frameworks/iso27001")
+		if _, err := ResolveRelative(k.RelativeTo(other), other); !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("expected ErrInvalidDomain before registration, got %v", err)
+		}
+		if err := RegisterDomain("kopexa.dev"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resolved, err := ResolveRelative(k.RelativeTo(other), other); err != nil || !resolved.Equals(k) {
+			t.Errorf("ResolveRelative() = (%v, %v), want %s", resolved, err, k)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		base := MustParse("//kopexa.com/frameworks/iso27001")
+		for _, s := range []string{
+			"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1?region=eu",
+			"//kopexa.com/frameworks/nist?region=eu",
+			"//isms.kopexa.com/tenants/acme?region=eu",
+		} {
+			k, _ := ParseWithOptions(s, WithQuery())
+			ref := k.RelativeTo(base)
+			if !strings.HasSuffix(ref, "?region=eu") {
+				t.Errorf("RelativeTo() = %q, want the query kept", ref)
+			}
+			if resolved, err := ResolveRelative(ref, base); err != nil || !resolved.Equals(k) {
+				t.Errorf("ResolveRelative(%q) = (%v, %v), want %s", ref, resolved, err, k)
+			}
+		}
+	})
+
+	t.Run("nil base", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001")
+		if got := k.RelativeTo(nil); got != k.String() {
+			t.Errorf("RelativeTo(nil) = %q, want %q", got, k.String())
+		}
+	})
+}
+
+func TestKRN_RelativePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		k       string
+		base    string
+		want    string
+		wantErr bool
+	}{
+		{"child", "//kopexa.com/frameworks/iso27001/controls/a-5-1", "//kopexa.com/frameworks/iso27001", "controls/a-5-1", false},
+		{"grandchild", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2", "//isms.kopexa.com/tenants/acme@v1", "workspaces/main/evidences/ev-1", false},
+		{"same resource", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", "", true},
+		{"sibling", "//kopexa.com/frameworks/nist", "//kopexa.com/frameworks/iso27001", "", true},
+		{"descendant as base", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a-5-1", "", true},
+		{"different service", "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1", "//kopexa.com/frameworks/iso27001", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, base := MustParse(tt.k), MustParse(tt.base)
+			got, err := k.RelativePath(base)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidKRN) {
+					t.Errorf("expected ErrInvalidKRN, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RelativePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil base", func(t *testing.T) {
+		if _, err := MustParse("//kopexa.com/frameworks/iso27001").RelativePath(nil); !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+}
+
+func TestResolveRelative_Errors(t *testing.T) {
+	base := MustParse("//kopexa.com/frameworks/iso27001")
+
+	tests := []struct {
+		name    string
+		ref     string
+		base    *KRN
+		wantErr error
+	}{
+		{"empty", "", base, ErrEmptyKRN},
+		{"relative without base", "controls/a-5-1", nil, ErrInvalidKRN},
+		{"rooted without base", "/frameworks/nist", nil, ErrInvalidKRN},
+		{"odd relative path", "controls", base, ErrInvalidKRN},
+		{"invalid resource ID", "controls/-bad", base, ErrInvalidResourceID},
+		{"invalid absolute", "//example.com/frameworks/x", base, ErrInvalidDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ResolveRelative(tt.ref, tt.base); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("absolute without base", func(t *testing.T) {
+		k, err := ResolveRelative("//kopexa.com/frameworks/nist", nil)
+		if err != nil || k.String() != "//kopexa.com/frameworks/nist" {
+			t.Errorf("ResolveRelative() = (%v, %v)", k, err)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import "sync"
+
+// Interner deduplicates parsed KRNs: every call to Intern with the same string
+// returns the same *KRN, so repeated KRNs are parsed and stored once and
+// Equals short-circuits on pointer identity.
+//
+// Interned KRNs are shared and must be treated as immutable; do not pass them
+// to UnmarshalJSON, UnmarshalText, UnmarshalYAML, UnmarshalBinary or Scan. The
+// pool only grows, so use one Interner per bounded working set (e.g. per batch)
+// rather than for unbounded user input. The zero value is ready to use, and an
+// Interner is safe for concurrent use.
+type Interner struct {
+	mu sync.RWMutex
+	m  map[string]*KRN
+}
+
+// Intern returns the shared KRN for s, parsing it on first use. Parse accepts
+// only canonical strings, so s is the canonical key. Invalid input returns the
+// Parse error and is not cached.
+func (in *Interner) Intern(s string) (*KRN, error) {
+	in.mu.RLock()
+	k, ok := in.m[s]
+	in.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.store(s, parsed), nil
+}
+
+// store adds k under s unless another goroutine interned s first, and returns
+// the pooled KRN.
+func (in *Interner) store(s string, k *KRN) *KRN {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if existing, ok := in.m[s]; ok {
+		return existing
+	}
+	if in.m == nil {
+		in.m = make(map[string]*KRN)
+	}
+	in.m[s] = k
+	return k
+}
+
+// Len returns the number of distinct KRNs in the pool.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.m)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestInterner(t *testing.T) {
+	var in Interner
+
+	a, err := in.Intern("//kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := in.Intern("//kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a != b {
+		t.Error("expected the same pointer for repeated input")
+	}
+	if !a.Equals(b) {
+		t.Error("expected interned KRNs to be equal")
+	}
+
+	c, _ := in.Intern("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+	if c == a {
+		t.Error("expected a distinct KRN for a different version")
+	}
+	if in.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", in.Len())
+	}
+
+	if _, err := in.Intern("invalid"); !errors.Is(err, ErrInvalidKRN) {
+		t.Errorf("expected ErrInvalidKRN, got %v", err)
+	}
+	if _, err := in.Intern(""); !errors.Is(err, ErrEmptyKRN) {
+		t.Errorf("expected ErrEmptyKRN, got %v", err)
+	}
+	if in.Len() != 2 {
+		t.Errorf("invalid input was cached: Len() = %d", in.Len())
+	}
+}
+
+func TestInterner_StoreRace(t *testing.T) {
+	var in Interner
+	const input = "//kopexa.com/frameworks/iso27001"
+
+	first := in.store(input, MustParse(input))
+	// A goroutine that parsed the same string concurrently must get the pooled KRN.
+	if got := in.store(input, MustParse(input)); got != first {
+		t.Error("expected the first stored KRN to win")
+	}
+}
+
+func TestInterner_Concurrent(t *testing.T) {
+	var in Interner
+	const workers = 8
+
+	results := make([][]*KRN, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				k, err := in.Intern(fmt.Sprintf("//kopexa.com/evidences/ev-%d", i%10))
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				results[w] = append(results[w], k)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if in.Len() != 10 {
+		t.Errorf("Len() = %d, want 10", in.Len())
+	}
+	for w := 1; w < workers; w++ {
+		for i := range results[w] {
+			if results[w][i] != results[0][i] {
+				t.Fatalf("worker %d got a different pointer for %s", w, results[w][i])
+			}
+		}
+	}
+}
+
+func BenchmarkInterner(b *testing.B) {
+	const input = "//kopexa.com/tenants/acme-corp/control-implementations/ci-123/evidences/ev-456@v1.2.3"
+
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = Parse(input)
+		}
+	})
+
+	b.Run("intern", func(b *testing.B) {
+		var in Interner
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = in.Intern(input)
+		}
+	})
+}
@@ -20,13 +20,25 @@ package krn
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 // Domain is the base domain for all KRNs.
 const Domain = "kopexa.com"
 
+// FormatVersion identifies the revision of the KRN grammar implemented by this package.
+// Storage layers can persist it alongside KRNs to detect when stored values were
+// written under an older grammar.
+//
+// It is bumped whenever the set of accepted or produced KRN strings changes
+// (e.g. a new version syntax or new allowed characters). It is not bumped for
+// API-only changes that leave the string format untouched.
+const FormatVersion = "1"
+
 // Error types for KRN parsing and validation.
 var (
 	ErrEmptyKRN          = errors.New("krn: empty KRN string")
@@ -35,14 +47,61 @@ var (
 	ErrInvalidResourceID = errors.New("krn: invalid resource ID")
 	ErrInvalidVersion    = errors.New("krn: invalid version format")
 	ErrResourceNotFound  = errors.New("krn: resource not found")
+	ErrInvalidConstraint = errors.New("krn: invalid version constraint")
+
+	// ErrInvalidService is returned for invalid service names. It wraps ErrInvalidDomain.
+	ErrInvalidService = fmt.Errorf("%w: invalid service name", ErrInvalidDomain)
+
+	// ErrServiceMismatch is returned when a KRN has a valid service other than
+	// the expected one, see ParseInService and ValidateServiceOwnership. It does
+	// not wrap ErrInvalidService, so a mismatch can be told apart from a
+	// malformed service name.
+	ErrServiceMismatch = errors.New("krn: service mismatch")
+
+	// ErrInvalidCollection is returned for collection names rejected by
+	// IsValidCollection in strict mode (see WithStrictCollections) and for
+	// collections that cannot be parsed back, such as "a/b". It wraps ErrInvalidKRN.
+	ErrInvalidCollection = fmt.Errorf("%w: invalid collection name", ErrInvalidKRN)
+
+	// ErrVersionRequired is returned for unversioned KRNs parsed with
+	// WithRequireVersion. It wraps ErrInvalidVersion.
+	ErrVersionRequired = fmt.Errorf("%w: version required", ErrInvalidVersion)
+
+	// ErrVersionForbidden is returned for versioned KRNs parsed with
+	// WithForbidVersion. It wraps ErrInvalidVersion.
+	ErrVersionForbidden = fmt.Errorf("%w: version not allowed", ErrInvalidVersion)
 )
 
+// ParseError describes where parsing a KRN string failed. Parse returns all
+// errors as *ParseError; errors.Is still matches the wrapped sentinel error
+// (ErrInvalidKRN, ErrInvalidResourceID, ...).
+type ParseError struct {
+	Input   string // the full input string
+	Value   string // the offending substring, e.g. a resource ID or version
+	Offset  int    // byte offset of Value in Input
+	Segment int    // index of the offending segment, or -1 if not within a segment
+	Err     error  // the underlying error, wrapping a sentinel error
+}
+
+// Error returns the underlying error message with the offset and segment, if known.
+func (e *ParseError) Error() string {
+	switch {
+	case e.Segment >= 0:
+		return fmt.Sprintf("%v (segment %d, offset %d)", e.Err, e.Segment, e.Offset)
+	case e.Offset > 0:
+		return fmt.Sprintf("%v (offset %d)", e.Err, e.Offset)
+	default:
+		return e.Err.Error()
+	}
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // Validation patterns.
 var (
-	// resourceIDPattern validates resource IDs: 1-200 chars, alphanumeric plus - _ .
-	// Cannot start or end with - or .
-	resourceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,198}[a-zA-Z0-9])?$|^[a-zA-Z0-9]$`)
-
 	// versionPattern validates version strings (OSCAL-compatible):
 	// - Alphanumeric, dots, dashes, underscores allowed
 	// - Cannot start or end with dash or dot
@@ -52,6 +111,10 @@ var (
 
 	// servicePattern validates service names: lowercase alphanumeric, 1-63 chars (DNS label)
 	servicePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,61}[a-z0-9]$|^[a-z]$`)
+
+	// collectionPattern validates collection names: lowercase alphanumeric plus -, 1-63 chars.
+	// Must start with a letter and cannot end with -
+	collectionPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,61}[a-z0-9]$|^[a-z]$`)
 )
 
 // Segment represents a collection/resource-id pair in a KRN path.
@@ -60,90 +123,222 @@ type Segment struct {
 	ResourceID string
 }
 
+// String returns the segment as "collection/resource-id".
+func (s Segment) String() string {
+	return s.Collection + "/" + s.ResourceID
+}
+
 // KRN represents a Kopexa Resource Name.
 type KRN struct {
+	domain   string // Base domain if parsed with WithDomain; empty means Domain
 	service  string // Optional service name (e.g., "catalog", "isms")
 	segments []Segment
 	version  string
+	query    string // Canonical url.Values encoding; only set with WithQuery
 }
 
 // Parse parses a KRN string and returns a KRN struct.
 func Parse(s string) (*KRN, error) {
+	return parse(s, Domain)
+}
+
+// parse parses a KRN string under the given base domain.
+func parse(s, domain string) (*KRN, error) {
+	return parseKRN(s, parseOptions{domain: domain})
+}
+
+// parseKRN parses a KRN string with the given options.
+// All errors are returned as *ParseError.
+func parseKRN(s string, o parseOptions) (*KRN, error) {
+	domain := o.domain
 	if s == "" {
-		return nil, ErrEmptyKRN
+		return nil, &ParseError{Input: s, Segment: -1, Err: ErrEmptyKRN}
 	}
 
 	// Must start with //
 	if !strings.HasPrefix(s, "//") {
-		return nil, fmt.Errorf("%w: must start with //", ErrInvalidKRN)
+		return nil, &ParseError{Input: s, Value: s, Segment: -1, Err: fmt.Errorf("%w: must start with //", ErrInvalidKRN)}
 	}
 
 	// Remove // prefix
-	s = s[2:]
+	body := s[2:]
+
+	// Extract the query if enabled
+	var query string
+	if o.allowQuery {
+		var err error
+		if body, query, err = cutQuery(s, body); err != nil {
+			return nil, err
+		}
+	}
 
 	// Extract version if present
+	end := 2 + len(body) // end of the version, before any query
 	var version string
-	if idx := strings.LastIndex(s, "@"); idx != -1 {
-		version = s[idx+1:]
-		s = s[:idx]
+	if idx := strings.LastIndex(body, "@"); idx != -1 {
+		version = body[idx+1:]
+		body = body[:idx]
 		if !IsValidVersion(version) {
-			return nil, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+			return nil, &ParseError{Input: s, Value: version, Offset: 2 + idx + 1, Segment: -1, Err: fmt.Errorf("%w: %s", ErrInvalidVersion, version)}
 		}
 	}
 
+	if err := o.checkVersionPolicy(s, version, end); err != nil {
+		return nil, err
+	}
+
+	if err := depthError(s, body, 2, o.maxDepth); err != nil {
+		return nil, err
+	}
+
 	// Split by /
-	parts := strings.Split(s, "/")
+	parts := strings.Split(body, "/")
 	if len(parts) < 3 {
-		return nil, fmt.Errorf("%w: must have at least domain/collection/id", ErrInvalidKRN)
+		return nil, &ParseError{Input: s, Value: body, Offset: 2, Segment: -1, Err: fmt.Errorf("%w: must have at least domain/collection/id", ErrInvalidKRN)}
 	}
 
 	// Parse domain - can be "kopexa.com" or "{service}.kopexa.com"
-	var service string
-	domain := parts[0]
-
-	switch {
-	case domain == Domain:
-		// Simple case: //kopexa.com/...
-		service = ""
-	case strings.HasSuffix(domain, "."+Domain):
-		// Service case: //{service}.kopexa.com/...
-		service = strings.TrimSuffix(domain, "."+Domain)
-		if !IsValidService(service) {
-			return nil, fmt.Errorf("%w: invalid service name %s", ErrInvalidDomain, service)
-		}
-	default:
-		return nil, fmt.Errorf("%w: expected %s or {service}.%s, got %s", ErrInvalidDomain, Domain, Domain, domain)
+	service, err := parseHost(parts[0], domain)
+	if err != nil {
+		return nil, &ParseError{Input: s, Value: parts[0], Offset: 2, Segment: -1, Err: err}
 	}
 
 	// Parse resource path (must be pairs of collection/id)
-	resourcePath := parts[1:]
-	if len(resourcePath)%2 != 0 {
-		return nil, fmt.Errorf("%w: resource path must be pairs of collection/id", ErrInvalidKRN)
+	segments, err := parseSegments(s, parts[1:], 2+len(parts[0])+1, o)
+	if err != nil {
+		return nil, err
 	}
 
-	segments := make([]Segment, 0, len(resourcePath)/2)
-	for i := 0; i < len(resourcePath); i += 2 {
-		collection := resourcePath[i]
-		resourceID := resourcePath[i+1]
+	if domain == Domain {
+		domain = ""
+	}
+
+	return &KRN{
+		domain:   domain,
+		service:  service,
+		segments: segments,
+		version:  version,
+		query:    query,
+	}, nil
+}
+
+// parseSegments parses the collection/id pairs of a resource path that starts
+// at byte offset in input. Strict collections and resource ID decoding are
+// controlled by o.
+func parseSegments(input string, path []string, offset int, o parseOptions) ([]Segment, error) {
+	if len(path)%2 != 0 {
+		return nil, pairingError(input, path, offset)
+	}
 
-		if collection == "" {
-			return nil, fmt.Errorf("%w: empty collection name", ErrInvalidKRN)
+	segments := make([]Segment, 0, len(path)/2)
+	for i := 0; i < len(path); i += 2 {
+		collection := path[i]
+		raw := path[i+1]
+		resourceID := raw
+		if o.decodeResourceIDs && strings.Contains(raw, "%") {
+			id, err := decodeResourceID(raw)
+			if err != nil {
+				return nil, &ParseError{Input: input, Value: raw, Offset: offset + len(collection) + 1, Segment: i / 2, Err: err}
+			}
+			resourceID = id
+		}
+		if err := segmentError(input, collection, resourceID, offset, i/2, o); err != nil {
+			return nil, err
 		}
-		if !IsValidResourceID(resourceID) {
-			return nil, fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID)
+		if o.uniqueCollections && slices.ContainsFunc(segments, func(s Segment) bool { return s.Collection == collection }) {
+			return nil, &ParseError{Input: input, Value: collection, Offset: offset, Segment: i / 2, Err: fmt.Errorf("%w: duplicate collection %s", ErrInvalidKRN, collection)}
 		}
 
 		segments = append(segments, Segment{
 			Collection: collection,
 			ResourceID: resourceID,
 		})
+		offset += len(collection) + len(raw) + 2
 	}
+	return segments, nil
+}
 
-	return &KRN{
-		service:  service,
-		segments: segments,
-		version:  version,
-	}, nil
+// depthError returns a *ParseError if body, the host and path of input starting
+// at byte offset, has more than maxDepth segments. It counts separators before
+// the path is split, so overly deep input is rejected without allocating its
+// segments. A maxDepth of 0 or less means unbounded.
+func depthError(input, body string, offset, maxDepth int) *ParseError {
+	if maxDepth <= 0 || strings.Count(body, "/") <= 2*maxDepth {
+		return nil
+	}
+
+	// Skip the host and the allowed segments.
+	i := 0
+	for range 2*maxDepth + 1 {
+		i += strings.IndexByte(body[i:], '/') + 1
+	}
+	return &ParseError{
+		Input:   input,
+		Value:   body[i:],
+		Offset:  offset + i,
+		Segment: maxDepth,
+		Err:     fmt.Errorf("%w: exceeds maximum depth %d", ErrInvalidKRN, maxDepth),
+	}
+}
+
+// pairingError reports a resource path with an odd number of elements.
+func pairingError(input string, path []string, offset int) *ParseError {
+	last := path[len(path)-1]
+	return &ParseError{
+		Input:   input,
+		Value:   last,
+		Offset:  offset + len(strings.Join(path, "/")) - len(last),
+		Segment: len(path) / 2,
+		Err:     fmt.Errorf("%w: resource path must be pairs of collection/id", ErrInvalidKRN),
+	}
+}
+
+// segmentError validates the collection/id pair at index that starts at byte
+// offset in input, applying the strict collection and resource ID length
+// options of o. It returns a *ParseError, or nil if the pair is valid.
+func segmentError(input, collection, resourceID string, offset, index int, o parseOptions) error {
+	idOffset := offset + len(collection) + 1
+	switch {
+	case collection == "":
+		return &ParseError{Input: input, Offset: offset, Segment: index, Err: fmt.Errorf("%w: empty collection name", ErrInvalidKRN)}
+	case o.strictCollections && !IsValidCollection(collection):
+		return &ParseError{Input: input, Value: collection, Offset: offset, Segment: index, Err: fmt.Errorf("%w: %s", ErrInvalidCollection, collection)}
+	case !IsValidResourceID(resourceID):
+		return &ParseError{Input: input, Value: resourceID, Offset: idOffset, Segment: index, Err: fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID)}
+	case o.maxResourceIDLength > 0 && len(resourceID) > o.maxResourceIDLength:
+		return &ParseError{Input: input, Value: resourceID, Offset: idOffset, Segment: index, Err: fmt.Errorf("%w: %s exceeds maximum length %d", ErrInvalidResourceID, resourceID, o.maxResourceIDLength)}
+	}
+	return nil
+}
+
+// parseHost extracts the service from a KRN host: "{domain}" or "{service}.{domain}".
+func parseHost(host, domain string) (string, error) {
+	switch {
+	case host == domain:
+		// Simple case: //kopexa.com/...
+		return "", nil
+	case strings.HasSuffix(host, "."+domain):
+		// Service case: //{service}.kopexa.com/...
+		service := strings.TrimSuffix(host, "."+domain)
+		if !IsValidService(service) {
+			return "", serviceError(service)
+		}
+		return service, nil
+	default:
+		return "", fmt.Errorf("%w: expected %s or {service}.%s, got %s", ErrInvalidDomain, domain, domain, host)
+	}
+}
+
+// ServiceFromHost extracts the service from a host name such as "catalog.kopexa.com".
+// For the bare base domain it returns an empty service and hasService false.
+// Returns ErrInvalidDomain if the host is not under the base domain and
+// ErrInvalidService if the service label is invalid.
+func ServiceFromHost(host string) (service string, hasService bool, err error) {
+	service, err = parseHost(host, Domain)
+	if err != nil {
+		return "", false, err
+	}
+	return service, service != "", nil
 }
 
 // MustParse parses a KRN string and panics on error.
@@ -155,18 +350,123 @@ func MustParse(s string) *KRN {
 	return krn
 }
 
+// ParseInService parses s like Parse within service-scoped code: a KRN without
+// a service gets the given service, and a KRN with a service must name the
+// same one, which guards against cross-service references leaking in. Returns
+// an error wrapping ErrServiceMismatch for a different (valid) service,
+// ErrInvalidService if service is not a valid service name, and otherwise the
+// errors of Parse.
+func ParseInService(s, service string) (*KRN, error) {
+	if !IsValidService(service) {
+		return nil, serviceError(service)
+	}
+
+	k, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.service != "" && k.service != service {
+		return nil, fmt.Errorf("%w: %s belongs to service %q, expected %q", ErrServiceMismatch, k, k.service, service)
+	}
+	k.service = service
+	return k, nil
+}
+
 // IsValid checks if a string is a valid KRN.
 func IsValid(s string) bool {
 	_, err := Parse(s)
 	return err == nil
 }
 
+// Validate checks a KRN string like Parse but reports every problem instead of
+// stopping at the first one: an invalid domain or service, each invalid
+// segment, an unpaired trailing path element and an invalid version, in the
+// order they appear in s. Every error is a *ParseError wrapping the same
+// sentinel Parse would return. If the input is empty or does not start with
+// "//", the single structural error is returned. Returns nil if s is a valid KRN.
+func Validate(s string) []error {
+	_, err := Parse(s)
+	if err == nil {
+		return nil
+	}
+	if !strings.HasPrefix(s, "//") {
+		return []error{err}
+	}
+
+	var errs []error
+	var versionErr error
+	body := s[2:]
+	if idx := strings.LastIndex(body, "@"); idx != -1 {
+		version := body[idx+1:]
+		body = body[:idx]
+		if !IsValidVersion(version) {
+			versionErr = &ParseError{Input: s, Value: version, Offset: 2 + idx + 1, Segment: -1, Err: fmt.Errorf("%w: %s", ErrInvalidVersion, version)}
+		}
+	}
+
+	parts := strings.Split(body, "/")
+	if len(parts) < 3 {
+		errs = append(errs, &ParseError{Input: s, Value: body, Offset: 2, Segment: -1, Err: fmt.Errorf("%w: must have at least domain/collection/id", ErrInvalidKRN)})
+	} else {
+		errs = append(errs, pathErrors(s, parts)...)
+	}
+
+	if versionErr != nil {
+		errs = append(errs, versionErr)
+	}
+	return errs
+}
+
+// pathErrors returns the host and segment errors for the "/"-separated parts
+// of a KRN body (host first, then the resource path).
+func pathErrors(input string, parts []string) []error {
+	var errs []error
+	if _, err := parseHost(parts[0], Domain); err != nil {
+		errs = append(errs, &ParseError{Input: input, Value: parts[0], Offset: 2, Segment: -1, Err: err})
+	}
+
+	path := parts[1:]
+	offset := 2 + len(parts[0]) + 1
+	pairs := path[:len(path)-len(path)%2]
+	for i := 0; i < len(pairs); i += 2 {
+		if err := segmentError(input, pairs[i], pairs[i+1], offset, i/2, parseOptions{}); err != nil {
+			errs = append(errs, err)
+		}
+		offset += len(pairs[i]) + len(pairs[i+1]) + 2
+	}
+	if len(path)%2 != 0 {
+		errs = append(errs, pairingError(input, path, 2+len(parts[0])+1))
+	}
+	return errs
+}
+
 // IsValidResourceID checks if a string is a valid resource ID.
+// Resource IDs are 1-MaxResourceIDLength ASCII characters: letters, digits, "-", "_" and ".",
+// starting and ending with a letter or digit. IsValidResourceID is on the hot
+// path of Parse, so it scans bytes instead of using a regular expression.
 func IsValidResourceID(id string) bool {
-	if id == "" || len(id) > 200 {
+	if id == "" || len(id) > MaxResourceIDLength {
 		return false
 	}
-	return resourceIDPattern.MatchString(id)
+	if !isAlphanumeric(id[0]) || !isAlphanumeric(id[len(id)-1]) {
+		return false
+	}
+	for i := 1; i < len(id)-1; i++ {
+		if c := id[i]; !isAlphanumeric(c) && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxResourceIDLength is the maximum length of a resource ID in bytes.
+// Use WithMaxResourceIDLength to enforce a stricter limit when parsing.
+const MaxResourceIDLength = 200
+
+// isAlphanumeric reports whether c is an ASCII letter or digit.
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
 }
 
 // IsValidVersion checks if a string is a valid version.
@@ -181,6 +481,7 @@ func IsValidVersion(v string) bool {
 
 // IsValidService checks if a string is a valid service name.
 // Service names must be lowercase, start with a letter, and contain only alphanumeric characters and hyphens.
+// Internationalized labels are accepted in their punycode form (e.g. "xn--bcher-kva").
 func IsValidService(s string) bool {
 	if s == "" {
 		return false
@@ -188,8 +489,54 @@ func IsValidService(s string) bool {
 	return servicePattern.MatchString(s)
 }
 
+// IsValidCollection checks if a string is a valid collection name.
+// Collection names must be lowercase, start with a letter, contain only
+// alphanumeric characters and hyphens, and be at most 63 characters long
+// (e.g. "frameworks", "control-implementations").
+//
+// Parse, NewChild and the Builder only require collections to be non-empty;
+// use ParseWithOptions with WithStrictCollections to enforce this check.
+func IsValidCollection(s string) bool {
+	if s == "" {
+		return false
+	}
+	return collectionPattern.MatchString(s)
+}
+
+// ContainsConfusables reports whether s contains non-ASCII characters.
+// KRNs are ASCII-only, so any such character (e.g. a Cyrillic "а" standing in
+// for a Latin "a") is either a mistake or an attempt to spoof an identifier.
+// Invalid UTF-8 is reported as well.
+func ContainsConfusables(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceError describes why service is not a valid service name.
+// Internationalized labels get a hint to use their punycode form.
+func serviceError(service string) error {
+	if ContainsConfusables(service) {
+		return fmt.Errorf("%w %q: non-ASCII characters are not allowed, use the punycode (xn--) form of the label", ErrInvalidService, service)
+	}
+	return fmt.Errorf("%w %s", ErrInvalidService, service)
+}
+
 // SafeResourceID converts a string to a valid resource ID by replacing invalid characters.
 func SafeResourceID(s string) string {
+	return SafeResourceIDWithLength(s, MaxResourceIDLength)
+}
+
+// SafeResourceIDWithLength is like SafeResourceID but truncates the result to
+// at most n bytes, e.g. to fit a 64-character database column. An n outside
+// 1..MaxResourceIDLength means MaxResourceIDLength.
+func SafeResourceIDWithLength(s string, n int) string {
+	if n <= 0 || n > MaxResourceIDLength {
+		n = MaxResourceIDLength
+	}
 	if s == "" {
 		return ""
 	}
@@ -209,9 +556,9 @@ func SafeResourceID(s string) string {
 	// Trim leading/trailing - and .
 	res = strings.Trim(res, "-.")
 
-	// Truncate to 200 characters
-	if len(res) > 200 {
-		res = res[:200]
+	// Truncate to the target length
+	if len(res) > n {
+		res = res[:n]
 		// Make sure we don't end with - or .
 		res = strings.TrimRight(res, "-.")
 	}
@@ -219,6 +566,39 @@ func SafeResourceID(s string) string {
 	return res
 }
 
+// Normalize parses a KRN string leniently and returns its canonical form.
+// It trims surrounding whitespace, collapses repeated slashes, drops a trailing
+// slash and lowercases the host (service and domain), so textually different
+// copies of the same KRN normalize to the same string. The result must still be
+// a valid KRN; otherwise Normalize returns the same errors as Parse.
+func Normalize(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "//") {
+		// Not a KRN at all; Parse reports the error.
+		_, err := Parse(s)
+		return "", err
+	}
+
+	// Cut the version at the last "@", like Parse.
+	body, version, hasVersion := SplitVersion(s[len("//"):])
+
+	parts := strings.FieldsFunc(body, func(r rune) bool { return r == '/' })
+	if len(parts) > 0 {
+		parts[0] = strings.ToLower(parts[0])
+	}
+
+	normalized := "//" + strings.Join(parts, "/")
+	if hasVersion {
+		normalized += "@" + version
+	}
+
+	k, err := Parse(normalized)
+	if err != nil {
+		return "", err
+	}
+	return k.String(), nil
+}
+
 // GetResource extracts a resource ID from a KRN string by collection name.
 func GetResource(krnString, collection string) (string, error) {
 	k, err := Parse(krnString)
@@ -237,7 +617,7 @@ func (k *KRN) String() string {
 		sb.WriteString(k.service)
 		sb.WriteString(".")
 	}
-	sb.WriteString(Domain)
+	sb.WriteString(k.baseDomain())
 
 	for _, seg := range k.segments {
 		sb.WriteString("/")
@@ -251,9 +631,58 @@ func (k *KRN) String() string {
 		sb.WriteString(k.version)
 	}
 
+	if k.query != "" {
+		sb.WriteString("?")
+		sb.WriteString(k.query)
+	}
+
 	return sb.String()
 }
 
+// Len returns the length in bytes of String() without building the string.
+func (k *KRN) Len() int {
+	n := len("//") + len(k.baseDomain())
+	if k.service != "" {
+		n += len(k.service) + len(".")
+	}
+	for _, seg := range k.segments {
+		n += len("/") + len(seg.Collection) + len("/") + len(seg.ResourceID)
+	}
+	if k.version != "" {
+		n += len("@") + len(k.version)
+	}
+	if k.query != "" {
+		n += len("?") + len(k.query)
+	}
+	return n
+}
+
+// FitsInBytes reports whether the string form of the KRN is at most limit bytes long.
+func (k *KRN) FitsInBytes(limit int) bool {
+	return k.Len() <= limit
+}
+
+// TruncateToFit returns a KRN whose string form is at most limit bytes long.
+// If the KRN already fits, an unchanged copy is returned. Otherwise the version is
+// dropped first and then trailing segments, one at a time, until it fits.
+// Truncation changes identity: the result names an unversioned or ancestor
+// resource, not the original one. Returns ErrInvalidKRN if even the root
+// resource exceeds limit.
+func (k *KRN) TruncateToFit(limit int) (*KRN, error) {
+	if k.FitsInBytes(limit) {
+		return k.Clone(), nil
+	}
+
+	t := k.WithoutVersion()
+	for len(t.segments) > 1 && !t.FitsInBytes(limit) {
+		t.segments = t.segments[:len(t.segments)-1]
+	}
+	if !t.FitsInBytes(limit) {
+		return nil, fmt.Errorf("%w: root resource %s exceeds %d bytes", ErrInvalidKRN, t, limit)
+	}
+	return t, nil
+}
+
 // Path returns the resource path without domain (alias: RelativeResourceName).
 func (k *KRN) Path() string {
 	var parts []string
@@ -273,6 +702,15 @@ func (k *KRN) Version() string {
 	return k.version
 }
 
+// Query returns the KRN's query attributes, e.g. region=eu for
+// //kopexa.com/frameworks/iso27001?region=eu. Queries are only accepted by
+// ParseWithOptions with WithQuery. The returned values are a copy; they are
+// empty if the KRN has no query.
+func (k *KRN) Query() url.Values {
+	q, _ := url.ParseQuery(k.query)
+	return q
+}
+
 // HasVersion returns true if the KRN has a version.
 func (k *KRN) HasVersion() bool {
 	return k.version != ""
@@ -288,11 +726,26 @@ func (k *KRN) HasService() bool {
 	return k.service != ""
 }
 
+// IsService reports whether the KRN belongs to the given service.
+// An empty service matches only KRNs without a service.
+func (k *KRN) IsService(service string) bool {
+	return k.service == service
+}
+
 // FullDomain returns the full domain including service if present.
 // Examples: "kopexa.com" or "catalog.kopexa.com"
 func (k *KRN) FullDomain() string {
 	if k.service != "" {
-		return k.service + "." + Domain
+		return k.service + "." + k.baseDomain()
+	}
+	return k.baseDomain()
+}
+
+// baseDomain returns the base domain of the KRN: Domain unless it was parsed
+// with WithDomain.
+func (k *KRN) baseDomain() string {
+	if k.domain != "" {
+		return k.domain
 	}
 	return Domain
 }
@@ -300,16 +753,18 @@ func (k *KRN) FullDomain() string {
 // WithService returns a new KRN with the specified service.
 func (k *KRN) WithService(service string) (*KRN, error) {
 	if !IsValidService(service) {
-		return nil, fmt.Errorf("%w: invalid service name %s", ErrInvalidDomain, service)
+		return nil, serviceError(service)
 	}
 
 	newSegments := make([]Segment, len(k.segments))
 	copy(newSegments, k.segments)
 
 	return &KRN{
+		domain:   k.domain,
 		service:  service,
 		segments: newSegments,
 		version:  k.version,
+		query:    k.query,
 	}, nil
 }
 
@@ -319,9 +774,11 @@ func (k *KRN) WithoutService() *KRN {
 	copy(newSegments, k.segments)
 
 	return &KRN{
+		domain:   k.domain,
 		service:  "",
 		segments: newSegments,
 		version:  k.version,
+		query:    k.query,
 	}
 }
 
@@ -335,6 +792,74 @@ func (k *KRN) ResourceID(collection string) (string, error) {
 	return "", fmt.Errorf("%w: %s", ErrResourceNotFound, collection)
 }
 
+// WithResourceID returns a new KRN with the resource ID of the first segment in
+// collection replaced by newID. The service, the other segments and the version
+// are kept. Returns ErrInvalidResourceID if newID is invalid and
+// ErrResourceNotFound if the collection is not present.
+func (k *KRN) WithResourceID(collection, newID string) (*KRN, error) {
+	if !IsValidResourceID(newID) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResourceID, newID)
+	}
+
+	for i, seg := range k.segments {
+		if seg.Collection != collection {
+			continue
+		}
+
+		newSegments := make([]Segment, len(k.segments))
+		copy(newSegments, k.segments)
+		newSegments[i].ResourceID = newID
+
+		return &KRN{
+			domain:   k.domain,
+			service:  k.service,
+			segments: newSegments,
+			version:  k.version,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, collection)
+}
+
+// MapSegments returns a new KRN with fn applied to each segment, e.g. to
+// rewrite legacy resource IDs with SafeResourceID. The service, version and
+// query are kept. Every resulting segment is validated like Parse does, so a bad transform
+// returns ErrInvalidKRN (empty collection), ErrInvalidCollection (a collection
+// containing "/", "@" or "?") or ErrInvalidResourceID instead of producing a
+// corrupt KRN.
+func (k *KRN) MapSegments(fn func(Segment) Segment) (*KRN, error) {
+	newSegments := make([]Segment, len(k.segments))
+	for i, seg := range k.segments {
+		newSegments[i] = fn(seg)
+		if err := validateSegment(newSegments[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &KRN{
+		domain:   k.domain,
+		service:  k.service,
+		segments: newSegments,
+		version:  k.version,
+		query:    k.query,
+	}, nil
+}
+
+// validateSegment checks a segment built outside of Parse. A collection
+// containing "/", "@" or "?" would not survive String and Parse, so it is
+// rejected with ErrInvalidCollection.
+func validateSegment(seg Segment) error {
+	if seg.Collection == "" {
+		return fmt.Errorf("%w: collection cannot be empty", ErrInvalidKRN)
+	}
+	if strings.ContainsAny(seg.Collection, "/@?") {
+		return fmt.Errorf("%w: %s", ErrInvalidCollection, seg.Collection)
+	}
+	if !IsValidResourceID(seg.ResourceID) {
+		return fmt.Errorf("%w: %s", ErrInvalidResourceID, seg.ResourceID)
+	}
+	return nil
+}
+
 // MustResourceID returns the resource ID for a given collection, panics if not found.
 func (k *KRN) MustResourceID(collection string) string {
 	id, err := k.ResourceID(collection)
@@ -344,6 +869,17 @@ func (k *KRN) MustResourceID(collection string) string {
 	return id
 }
 
+// EnclosingID returns the resource ID for a given collection and whether it was found.
+// It is the non-error form of ResourceID, e.g. for the ID of the enclosing tenant.
+func (k *KRN) EnclosingID(collection string) (string, bool) {
+	for _, seg := range k.segments {
+		if seg.Collection == collection {
+			return seg.ResourceID, true
+		}
+	}
+	return "", false
+}
+
 // HasResource returns true if the KRN has a resource with the given collection.
 func (k *KRN) HasResource(collection string) bool {
 	for _, seg := range k.segments {
@@ -354,6 +890,28 @@ func (k *KRN) HasResource(collection string) bool {
 	return false
 }
 
+// HasSegment returns true if any segment matches both collection and resourceID.
+func (k *KRN) HasSegment(collection, resourceID string) bool {
+	for _, seg := range k.segments {
+		if seg.Collection == collection && seg.ResourceID == resourceID {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant returns the resource ID of the "tenants" segment and true, or "" and
+// false if the KRN is not tenant-scoped.
+func (k *KRN) Tenant() (string, bool) {
+	return k.EnclosingID(string(ResourceTypeTenants))
+}
+
+// IsTenantScoped returns true if the KRN has a "tenants" segment.
+func (k *KRN) IsTenantScoped() bool {
+	_, ok := k.Tenant()
+	return ok
+}
+
 // Basename returns the last resource ID in the path.
 func (k *KRN) Basename() string {
 	if len(k.segments) == 0 {
@@ -370,6 +928,26 @@ func (k *KRN) BasenameCollection() string {
 	return k.segments[len(k.segments)-1].Collection
 }
 
+// ResourceType is the type of a resource, named after its collection.
+type ResourceType string
+
+// Well-known Kopexa resource types.
+const (
+	ResourceTypeFrameworks             ResourceType = "frameworks"
+	ResourceTypeControls               ResourceType = "controls"
+	ResourceTypeTenants                ResourceType = "tenants"
+	ResourceTypeWorkspaces             ResourceType = "workspaces"
+	ResourceTypeEvidences              ResourceType = "evidences"
+	ResourceTypeControlImplementations ResourceType = "control-implementations"
+)
+
+// ResourceType returns the type of the resource the KRN names: its last
+// collection (see BasenameCollection). Collections without a constant are
+// returned as-is.
+func (k *KRN) ResourceType() ResourceType {
+	return ResourceType(k.BasenameCollection())
+}
+
 // Parent returns a new KRN without the last segment, or nil if this is a root resource.
 func (k *KRN) Parent() *KRN {
 	if len(k.segments) <= 1 {
@@ -380,12 +958,34 @@ func (k *KRN) Parent() *KRN {
 	copy(newSegments, k.segments[:len(k.segments)-1])
 
 	return &KRN{
+		domain:   k.domain,
 		service:  k.service,
 		segments: newSegments,
 		version:  "", // Parent doesn't inherit version
 	}
 }
 
+// IsRoot returns true if the KRN is a top-level resource (Depth() == 1).
+func (k *KRN) IsRoot() bool {
+	return len(k.segments) == 1
+}
+
+// Root returns a new KRN with only the first segment, e.g.
+// //kopexa.com/frameworks/iso27001 for any control of that framework.
+// The service is kept and the version is dropped, as with Parent, so the root
+// of a versioned root resource is its unversioned form.
+// Returns nil for the zero KRN.
+func (k *KRN) Root() *KRN {
+	if len(k.segments) == 0 {
+		return nil
+	}
+	return &KRN{
+		domain:   k.domain,
+		service:  k.service,
+		segments: []Segment{k.segments[0]},
+	}
+}
+
 // WithVersion returns a new KRN with the specified version.
 func (k *KRN) WithVersion(version string) (*KRN, error) {
 	if !IsValidVersion(version) {
@@ -396,6 +996,7 @@ func (k *KRN) WithVersion(version string) (*KRN, error) {
 	copy(newSegments, k.segments)
 
 	return &KRN{
+		domain:   k.domain,
 		service:  k.service,
 		segments: newSegments,
 		version:  version,
@@ -408,23 +1009,63 @@ func (k *KRN) WithoutVersion() *KRN {
 	copy(newSegments, k.segments)
 
 	return &KRN{
+		domain:   k.domain,
 		service:  k.service,
 		segments: newSegments,
 		version:  "",
 	}
 }
 
+// Clone returns a deep copy of the KRN with an independently allocated
+// segments slice. Cloning a nil KRN returns nil.
+func (k *KRN) Clone() *KRN {
+	if k == nil {
+		return nil
+	}
+
+	newSegments := make([]Segment, len(k.segments))
+	copy(newSegments, k.segments)
+
+	return &KRN{
+		domain:   k.domain,
+		service:  k.service,
+		segments: newSegments,
+		version:  k.version,
+		query:    k.query,
+	}
+}
+
+// NormalizeParentVersion returns a copy of the KRN suitable for use as an ancestor
+// reference levels deep. Versions only apply to leaves, so when levels > 0 the
+// version is removed, matching how Parent() drops it. When levels <= 0 the KRN is
+// the leaf itself and the copy keeps its version.
+func (k *KRN) NormalizeParentVersion(levels int) *KRN {
+	if levels > 0 {
+		return k.WithoutVersion()
+	}
+	return k.Clone()
+}
+
+// FormatVersion returns the grammar revision the KRN was produced under.
+// It always equals the package-level FormatVersion constant.
+func (k *KRN) FormatVersion() string {
+	return FormatVersion
+}
+
 // Equals checks if two KRNs are equal.
 func (k *KRN) Equals(other *KRN) bool {
 	if other == nil {
 		return false
 	}
+	if k == other {
+		return true
+	}
 	return k.String() == other.String()
 }
 
 // EqualsString checks if the KRN equals another KRN string.
 func (k *KRN) EqualsString(other string) bool {
-	otherKRN, err := Parse(other)
+	otherKRN, err := parse(other, k.baseDomain())
 	if err != nil {
 		return false
 	}
@@ -438,6 +1079,41 @@ func (k *KRN) Segments() []Segment {
 	return result
 }
 
+// Collections returns the distinct collection names of the KRN in path order,
+// e.g. ["frameworks", "controls"] for //kopexa.com/frameworks/iso27001/controls/a-5-1.
+// A collection that appears more than once is listed at its first occurrence.
+func (k *KRN) Collections() []string {
+	collections := make([]string, 0, len(k.segments))
+	for _, seg := range k.segments {
+		if !slices.Contains(collections, seg.Collection) {
+			collections = append(collections, seg.Collection)
+		}
+	}
+	return collections
+}
+
+// HasDuplicateCollections reports whether a collection name appears in more than
+// one segment, e.g. //kopexa.com/controls/a/controls/b. Such KRNs are almost
+// always a data error, and ResourceID only finds the first occurrence. Use
+// WithUniqueCollections to reject them when parsing.
+func (k *KRN) HasDuplicateCollections() bool {
+	return len(k.Collections()) != len(k.segments)
+}
+
+// ToMap returns the segments as a collection -> resource ID map, e.g. for
+// templating and structured logging. Use Segments for the ordered pairs.
+// If a collection appears more than once, the first occurrence wins, so
+// ToMap()[c] always agrees with ResourceID(c).
+func (k *KRN) ToMap() map[string]string {
+	m := make(map[string]string, len(k.segments))
+	for _, seg := range k.segments {
+		if _, ok := m[seg.Collection]; !ok {
+			m[seg.Collection] = seg.ResourceID
+		}
+	}
+	return m
+}
+
 // Depth returns the number of resource levels in the KRN.
 func (k *KRN) Depth() int {
 	return len(k.segments)
@@ -463,12 +1139,46 @@ func NewChild(parent *KRN, collection, resourceID string) (*KRN, error) {
 	}
 
 	return &KRN{
+		domain:   parent.domain,
 		service:  parent.service,
 		segments: newSegments,
 		version:  "", // Child doesn't inherit version
 	}, nil
 }
 
+// FromSegments creates a KRN from its parts, e.g. when loading segments from
+// database rows. An empty service or version is omitted. Returns
+// ErrInvalidService, ErrInvalidKRN (no segments or an empty collection),
+// ErrInvalidCollection (a collection containing "/", "@" or "?"),
+// ErrInvalidResourceID or ErrInvalidVersion for invalid parts, validating the
+// segments like UnmarshalBinary and MapSegments.
+// The segments are copied, so later changes to the slice do not affect the KRN.
+func FromSegments(service string, segments []Segment, version string) (*KRN, error) {
+	if service != "" && !IsValidService(service) {
+		return nil, serviceError(service)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: must have at least one resource", ErrInvalidKRN)
+	}
+	for _, seg := range segments {
+		if err := validateSegment(seg); err != nil {
+			return nil, err
+		}
+	}
+	if version != "" && !IsValidVersion(version) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+	}
+
+	newSegments := make([]Segment, len(segments))
+	copy(newSegments, segments)
+
+	return &KRN{
+		service:  service,
+		segments: newSegments,
+		version:  version,
+	}, nil
+}
+
 // NewChildFromString creates a new KRN as a child of the given parent KRN string.
 func NewChildFromString(parentKRN, collection, resourceID string) (*KRN, error) {
 	parent, err := Parse(parentKRN)
@@ -478,12 +1188,41 @@ func NewChildFromString(parentKRN, collection, resourceID string) (*KRN, error)
 	return NewChild(parent, collection, resourceID)
 }
 
+// Append returns a new KRN with collection/resourceID appended to k.
+// It is the method form of NewChild(k, collection, resourceID): it validates
+// the same way, keeps the service and does not inherit the version.
+// For several levels at once, use From(k).Resource(...).Build().
+func (k *KRN) Append(collection, resourceID string) (*KRN, error) {
+	return NewChild(k, collection, resourceID)
+}
+
+// Children returns one child KRN per id, each built like k.Append(collection, id),
+// in the order of ids, e.g. to fan out evidences ev-1, ev-2 and ev-3 under a
+// control implementation. It fails fast: the first invalid id returns an error
+// naming its index and value and wrapping ErrInvalidResourceID. An empty
+// collection returns ErrInvalidKRN. Returns an empty slice if ids is empty.
+func (k *KRN) Children(collection string, ids []string) ([]*KRN, error) {
+	children := make([]*KRN, 0, len(ids))
+	for i, id := range ids {
+		child, err := NewChild(k, collection, id)
+		if err != nil {
+			return nil, fmt.Errorf("id %d (%q): %w", i, id, err)
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
 // Builder provides a fluent API for building KRNs.
+//
+// Every call is validated, even after an earlier call failed, so Errors can
+// report all mistakes at once. Build fails if any call failed.
 type Builder struct {
+	domain   string
 	service  string
 	segments []Segment
 	version  string
-	err      error
+	errs     []error
 }
 
 // New creates a new KRN builder.
@@ -493,14 +1232,39 @@ func New() *Builder {
 	}
 }
 
-// Service sets the service for the KRN (optional).
-func (b *Builder) Service(service string) *Builder {
-	if b.err != nil {
-		return b
+// NewFrom creates a new KRN builder seeded with the base domain, service and segments of k.
+// The version of k is not carried over, matching NewChild: extending a KRN
+// produces a different resource, so callers set a version explicitly if needed.
+// The segments are copied, so building does not affect k.
+func NewFrom(k *KRN) *Builder {
+	if k == nil {
+		return &Builder{
+			segments: make([]Segment, 0),
+			errs:     []error{fmt.Errorf("%w: source KRN cannot be nil", ErrInvalidKRN)},
+		}
 	}
 
+	segments := make([]Segment, len(k.segments))
+	copy(segments, k.segments)
+
+	return &Builder{
+		domain:   k.domain,
+		service:  k.service,
+		segments: segments,
+	}
+}
+
+// From is shorthand for NewFrom, for fluent multi-level extensions of a parent:
+//
+//	k, err := krn.From(parent).Resource("controls", "a-5-1").Build()
+func From(parent *KRN) *Builder {
+	return NewFrom(parent)
+}
+
+// Service sets the service for the KRN (optional).
+func (b *Builder) Service(service string) *Builder {
 	if !IsValidService(service) {
-		b.err = fmt.Errorf("%w: invalid service name %s", ErrInvalidDomain, service)
+		b.errs = append(b.errs, serviceError(service))
 		return b
 	}
 
@@ -510,17 +1274,13 @@ func (b *Builder) Service(service string) *Builder {
 
 // Resource adds a resource segment to the builder.
 func (b *Builder) Resource(collection, resourceID string) *Builder {
-	if b.err != nil {
-		return b
-	}
-
 	if collection == "" {
-		b.err = fmt.Errorf("%w: collection cannot be empty", ErrInvalidKRN)
+		b.errs = append(b.errs, fmt.Errorf("%w: collection cannot be empty", ErrInvalidKRN))
 		return b
 	}
 
 	if !IsValidResourceID(resourceID) {
-		b.err = fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID)
+		b.errs = append(b.errs, fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID))
 		return b
 	}
 
@@ -533,12 +1293,8 @@ func (b *Builder) Resource(collection, resourceID string) *Builder {
 
 // Version sets the version for the KRN.
 func (b *Builder) Version(version string) *Builder {
-	if b.err != nil {
-		return b
-	}
-
 	if !IsValidVersion(version) {
-		b.err = fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+		b.errs = append(b.errs, fmt.Errorf("%w: %s", ErrInvalidVersion, version))
 		return b
 	}
 
@@ -546,10 +1302,22 @@ func (b *Builder) Version(version string) *Builder {
 	return b
 }
 
-// Build creates the KRN. Returns nil and error if any error occurred during building.
+// Errors returns every validation error from the builder calls so far, in call
+// order, or nil if there were none. The first entry is the earliest mistake.
+func (b *Builder) Errors() []error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	errs := make([]error, len(b.errs))
+	copy(errs, b.errs)
+	return errs
+}
+
+// Build creates the KRN. Returns nil and an error if any builder call failed;
+// the error joins all of them (see Errors), so errors.Is matches each sentinel.
 func (b *Builder) Build() (*KRN, error) {
-	if b.err != nil {
-		return nil, b.err
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
 	}
 
 	if len(b.segments) == 0 {
@@ -557,6 +1325,7 @@ func (b *Builder) Build() (*KRN, error) {
 	}
 
 	return &KRN{
+		domain:   b.domain,
 		service:  b.service,
 		segments: b.segments,
 		version:  b.version,
@@ -5,6 +5,10 @@ package krn
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -376,6 +380,105 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_ParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		value    string
+		offset   int
+		segment  int
+		sentinel error
+	}{
+		{"empty", "", "", 0, -1, ErrEmptyKRN},
+		{"missing prefix", "kopexa.com/frameworks/iso27001", "kopexa.com/frameworks/iso27001", 0, -1, ErrInvalidKRN},
+		{"invalid version", "//kopexa.com/frameworks/iso27001@-bad", "-bad", 33, -1, ErrInvalidVersion},
+		{"too short", "//kopexa.com/frameworks", "kopexa.com/frameworks", 2, -1, ErrInvalidKRN},
+		{"invalid domain", "//example.com/frameworks/iso27001", "example.com", 2, -1, ErrInvalidDomain},
+		{"invalid service", "//Catalog.kopexa.com/frameworks/iso27001", "Catalog.kopexa.com", 2, -1, ErrInvalidService},
+		{"invalid first ID", "//kopexa.com/frameworks/-bad", "-bad", 24, 0, ErrInvalidResourceID},
+		{"invalid nested ID", "//kopexa.com/frameworks/iso27001/controls/a_5_1./x/y", "a_5_1.", 42, 1, ErrInvalidResourceID},
+		{"invalid ID with service", "//isms.kopexa.com/tenants/acme/workspaces/-main", "-main", 42, 1, ErrInvalidResourceID},
+		{"empty collection", "//kopexa.com/frameworks/iso27001//a-5-1", "", 33, 1, ErrInvalidKRN},
+		{"unpaired collection", "//kopexa.com/frameworks/iso27001/controls", "controls", 33, 1, ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("expected error %v, got %v", tt.sentinel, err)
+			}
+
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected *ParseError, got %T", err)
+			}
+			if pe.Input != tt.input || pe.Value != tt.value || pe.Offset != tt.offset || pe.Segment != tt.segment {
+				t.Errorf("ParseError = {Value: %q, Offset: %d, Segment: %d}, want {Value: %q, Offset: %d, Segment: %d}",
+					pe.Value, pe.Offset, pe.Segment, tt.value, tt.offset, tt.segment)
+			}
+			if tt.value != "" && tt.input[pe.Offset:pe.Offset+len(pe.Value)] != pe.Value {
+				t.Errorf("offset %d does not point at %q in %q", pe.Offset, pe.Value, tt.input)
+			}
+		})
+	}
+
+	t.Run("messages", func(t *testing.T) {
+		_, err := Parse("")
+		if err.Error() != ErrEmptyKRN.Error() {
+			t.Errorf("got %q", err.Error())
+		}
+		_, err = Parse("//kopexa.com/frameworks/iso27001@-bad")
+		if !strings.Contains(err.Error(), "(offset 33)") {
+			t.Errorf("got %q", err.Error())
+		}
+		_, err = Parse("//kopexa.com/frameworks/-bad")
+		if !strings.Contains(err.Error(), "(segment 0, offset 24)") {
+			t.Errorf("got %q", err.Error())
+		}
+	})
+}
+
+func TestServiceFromHost(t *testing.T) {
+	tests := []struct {
+		host        string
+		wantService string
+		wantHas     bool
+		wantErr     error
+	}{
+		{host: "kopexa.com", wantService: "", wantHas: false},
+		{host: "catalog.kopexa.com", wantService: "catalog", wantHas: true},
+		{host: "xn--bcher-kva.kopexa.com", wantService: "xn--bcher-kva", wantHas: true},
+		{host: "example.com", wantErr: ErrInvalidDomain},
+		{host: "kopexa.com.evil.com", wantErr: ErrInvalidDomain},
+		{host: "", wantErr: ErrInvalidDomain},
+		{host: "Catalog.kopexa.com", wantErr: ErrInvalidService},
+		{host: "a.b.kopexa.com", wantErr: ErrInvalidService},
+		{host: ".kopexa.com", wantErr: ErrInvalidService},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			service, has, err := ServiceFromHost(tt.host)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				if service != "" || has {
+					t.Errorf("expected empty result on error, got (%q, %v)", service, has)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if service != tt.wantService || has != tt.wantHas {
+				t.Errorf("ServiceFromHost(%q) = (%q, %v), want (%q, %v)", tt.host, service, has, tt.wantService, tt.wantHas)
+			}
+		})
+	}
+}
+
 func TestMustParse(t *testing.T) {
 	t.Run("valid KRN", func(t *testing.T) {
 		k := MustParse("//kopexa.com/frameworks/iso27001")
@@ -394,6 +497,51 @@ func TestMustParse(t *testing.T) {
 	})
 }
 
+func TestParseInService(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		service string
+		want    string
+		wantErr error
+	}{
+		{"applies service", "//kopexa.com/frameworks/iso27001@v1", "catalog", "//catalog.kopexa.com/frameworks/iso27001@v1", nil},
+		{"matching service", "//catalog.kopexa.com/frameworks/iso27001", "catalog", "//catalog.kopexa.com/frameworks/iso27001", nil},
+		{"other service", "//isms.kopexa.com/frameworks/iso27001", "catalog", "", ErrServiceMismatch},
+		{"invalid service argument", "//kopexa.com/frameworks/iso27001", "Catalog", "", ErrInvalidService},
+		{"empty service argument", "//kopexa.com/frameworks/iso27001", "", "", ErrInvalidService},
+		{"invalid input", "//kopexa.com/frameworks/-bad", "catalog", "", ErrInvalidResourceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseInService(tt.input, tt.service)
+			if tt.wantErr != nil {
+				if k != nil || !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got (%v, %v)", tt.wantErr, k, err)
+				}
+				if mismatch := tt.wantErr == ErrServiceMismatch; mismatch != errors.Is(err, ErrServiceMismatch) || mismatch && errors.Is(err, ErrInvalidService) {
+					t.Errorf("a mismatch must be distinguishable from other errors: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.String() != tt.want {
+				t.Errorf("ParseInService() = %s, want %s", k, tt.want)
+			}
+		})
+	}
+
+	t.Run("mismatch message", func(t *testing.T) {
+		_, err := ParseInService("//isms.kopexa.com/frameworks/iso27001", "catalog")
+		if want := `belongs to service "isms", expected "catalog"`; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	})
+}
+
 func TestIsValid(t *testing.T) {
 	tests := []struct {
 		input string
@@ -415,6 +563,55 @@ func TestIsValid(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		sentinels []error
+		segments  []int
+	}{
+		{"valid", "//kopexa.com/frameworks/iso27001/controls/a-5-1@v1", nil, nil},
+		{"empty", "", []error{ErrEmptyKRN}, []int{-1}},
+		{"missing prefix", "kopexa.com/frameworks/-bad", []error{ErrInvalidKRN}, []int{-1}},
+		{"single error", "//kopexa.com/frameworks/-bad", []error{ErrInvalidResourceID}, []int{0}},
+		{
+			"all problems",
+			"//Catalog.kopexa.com/frameworks/-bad//a-5-1/evidences/e_1./policies@-v",
+			[]error{ErrInvalidService, ErrInvalidResourceID, ErrInvalidKRN, ErrInvalidResourceID, ErrInvalidKRN, ErrInvalidVersion},
+			[]int{-1, 0, 1, 2, 3, -1},
+		},
+		{"too short with bad version", "//kopexa.com/frameworks@-v", []error{ErrInvalidKRN, ErrInvalidVersion}, []int{-1, -1}},
+		{"bad domain and ID", "//example.com/frameworks/-bad", []error{ErrInvalidDomain, ErrInvalidResourceID}, []int{-1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate(tt.input)
+			if len(errs) != len(tt.sentinels) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs), len(tt.sentinels), errs)
+			}
+			for i, err := range errs {
+				if !errors.Is(err, tt.sentinels[i]) {
+					t.Errorf("error %d: expected %v, got %v", i, tt.sentinels[i], err)
+				}
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Fatalf("error %d: expected *ParseError, got %T", i, err)
+				}
+				if pe.Segment != tt.segments[i] {
+					t.Errorf("error %d: segment = %d, want %d", i, pe.Segment, tt.segments[i])
+				}
+				if pe.Value != "" && tt.input[pe.Offset:pe.Offset+len(pe.Value)] != pe.Value {
+					t.Errorf("error %d: offset %d does not point at %q", i, pe.Offset, pe.Value)
+				}
+			}
+			if (len(errs) == 0) != IsValid(tt.input) {
+				t.Errorf("Validate and IsValid disagree for %q", tt.input)
+			}
+		})
+	}
+}
+
 func TestIsValidResourceID(t *testing.T) {
 	tests := []struct {
 		input string
@@ -451,6 +648,145 @@ func TestIsValidResourceID(t *testing.T) {
 	}
 }
 
+func TestIsValidCollection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"frameworks", true},
+		{"control-implementations", true},
+		{"v2", true},
+		{"a", true},
+		{strings.Repeat("a", 63), true},
+		{"", false},
+		{"Frameworks", false},
+		{"my collection", false},
+		{"frame_works", false},
+		{"frame.works", false},
+		{"2frameworks", false},
+		{"-frameworks", false},
+		{"frameworks-", false},
+		{strings.Repeat("a", 64), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := IsValidCollection(tt.input); got != tt.want {
+				t.Errorf("IsValidCollection(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsConfusables(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"empty", "", false},
+		{"ascii", "acme-corp", false},
+		{"ascii krn", "//kopexa.com/tenants/acme-corp", false},
+		{"cyrillic a", "\u0430cme-corp", true},
+		{"greek omicron", "isms-c\u03bfre", true},
+		{"fullwidth digit", "iso\uff12\uff17001", true},
+		{"latin accent", "caf\u00e9", true},
+		{"zero width space", "acme\u200bcorp", true},
+		{"invalid utf8", "acme\xffcorp", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContainsConfusables(tt.input); got != tt.want {
+				t.Errorf("ContainsConfusables(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// resourceIDRegexp is the reference definition of a valid resource ID.
+// IsValidResourceID must agree with it for every input.
+var resourceIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,198}[a-zA-Z0-9])?$|^[a-zA-Z0-9]$`)
+
+func TestIsValidResourceID_MatchesRegexp(t *testing.T) {
+	// Every string of up to three bytes over an alphabet covering each
+	// character class, plus a non-ASCII byte.
+	alphabet := []byte{'a', 'Z', '0', '9', '-', '_', '.', ' ', '/', '@', 0x00, 0xc3}
+	inputs := []string{""}
+	prev := []string{""}
+	for range 3 {
+		var next []string
+		for _, p := range prev {
+			for _, c := range alphabet {
+				next = append(next, p+string(c))
+			}
+		}
+		inputs = append(inputs, next...)
+		prev = next
+	}
+
+	for _, n := range []int{198, 199, 200, 201} {
+		inputs = append(inputs,
+			strings.Repeat("a", n),
+			"a"+strings.Repeat("-", n-2)+"a",
+			"a"+strings.Repeat("a", n-2)+"-",
+		)
+	}
+
+	for _, input := range inputs {
+		if got, want := IsValidResourceID(input), resourceIDRegexp.MatchString(input); got != want {
+			t.Errorf("IsValidResourceID(%q) = %v, regexp says %v", input, got, want)
+		}
+	}
+}
+
+func FuzzIsValidResourceID(f *testing.F) {
+	for _, seed := range []string{"", "a", "a-5-1", "-a", "a.", "a b", strings.Repeat("x", 200)} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if got, want := IsValidResourceID(input), resourceIDRegexp.MatchString(input); got != want {
+			t.Errorf("IsValidResourceID(%q) = %v, regexp says %v", input, got, want)
+		}
+	})
+}
+
+func TestIsValidResourceID_RejectsNonASCII(t *testing.T) {
+	inputs := []string{
+		"\u0430cme",        // Cyrillic a
+		"acm\u0435",        // Cyrillic e
+		"is\u043cs",        // Cyrillic m
+		"c\u03bfntrol",     // Greek omicron
+		"\uff41bc",         // fullwidth a
+		"a\u0301",          // combining acute accent
+		"caf\u00e9",        // precomposed e-acute
+		"a\u200bb",         // zero width space
+		"\u0661\u0662",     // Arabic-Indic digits
+		"a\xffb",           // invalid UTF-8
+		"\u212a",           // Kelvin sign, folds to K
+		"\u017f",           // long s, folds to s
+		"ok-\u0391-ok",     // Greek capital alpha
+		"\U0001d41a\u0062", // mathematical bold a
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if !ContainsConfusables(input) {
+				t.Errorf("ContainsConfusables(%q) = false, want true", input)
+			}
+			if IsValidResourceID(input) {
+				t.Errorf("IsValidResourceID(%q) = true, want false", input)
+			}
+			if _, err := Parse("//kopexa.com/tenants/" + input); !errors.Is(err, ErrInvalidResourceID) {
+				t.Errorf("Parse with %q: expected ErrInvalidResourceID, got %v", input, err)
+			}
+			if safe := SafeResourceID(input); ContainsConfusables(safe) {
+				t.Errorf("SafeResourceID(%q) = %q still contains non-ASCII", input, safe)
+			}
+		})
+	}
+}
+
 func TestIsValidVersion(t *testing.T) {
 	tests := []struct {
 		input string
@@ -527,6 +863,8 @@ func TestIsValidService(t *testing.T) {
 		{"Service", false},      // mixed case not allowed
 		{"service_name", false}, // underscores not allowed
 		{"service.name", false}, // dots not allowed
+		{"xn--bcher-kva", true}, // punycode label
+		{"b\u00fccher", false},  // raw Unicode label
 	}
 
 	for _, tt := range tests {
@@ -538,6 +876,53 @@ func TestIsValidService(t *testing.T) {
 	}
 }
 
+func TestParse_InternationalizedService(t *testing.T) {
+	t.Run("punycode service", func(t *testing.T) {
+		k, err := Parse("//xn--bcher-kva.kopexa.com/frameworks/iso27001")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if k.Service() != "xn--bcher-kva" {
+			t.Errorf("expected service xn--bcher-kva, got %s", k.Service())
+		}
+	})
+
+	t.Run("unicode service", func(t *testing.T) {
+		_, err := Parse("//b\u00fccher.kopexa.com/frameworks/iso27001")
+		if !errors.Is(err, ErrInvalidService) {
+			t.Fatalf("expected ErrInvalidService, got %v", err)
+		}
+		if !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("expected error to wrap ErrInvalidDomain, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "punycode") {
+			t.Errorf("expected punycode hint, got %q", err.Error())
+		}
+	})
+
+	t.Run("ascii invalid service has no punycode hint", func(t *testing.T) {
+		_, err := Parse("//Catalog.kopexa.com/frameworks/iso27001")
+		if !errors.Is(err, ErrInvalidService) {
+			t.Fatalf("expected ErrInvalidService, got %v", err)
+		}
+		if strings.Contains(err.Error(), "punycode") {
+			t.Errorf("unexpected punycode hint: %q", err.Error())
+		}
+	})
+
+	t.Run("builder and WithService", func(t *testing.T) {
+		_, err := New().Service("b\u00fccher").Resource("frameworks", "iso27001").Build()
+		if !errors.Is(err, ErrInvalidService) || !strings.Contains(err.Error(), "punycode") {
+			t.Errorf("Builder.Service: expected ErrInvalidService with punycode hint, got %v", err)
+		}
+
+		_, err = MustParse("//kopexa.com/frameworks/iso27001").WithService("b\u00fccher")
+		if !errors.Is(err, ErrInvalidService) || !strings.Contains(err.Error(), "punycode") {
+			t.Errorf("WithService: expected ErrInvalidService with punycode hint, got %v", err)
+		}
+	})
+}
+
 func TestSafeResourceID(t *testing.T) {
 	tests := []struct {
 		input string
@@ -566,6 +951,93 @@ func TestSafeResourceID(t *testing.T) {
 	}
 }
 
+func TestSafeResourceIDWithLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  string
+	}{
+		{"shorter than limit", "acme corp", 64, "acme-corp"},
+		{"truncated", strings.Repeat("a", 100), 64, strings.Repeat("a", 64)},
+		{"no trailing dash after truncation", "acme corp", 5, "acme"},
+		{"zero means default", strings.Repeat("a", 250), 0, strings.Repeat("a", MaxResourceIDLength)},
+		{"above default is capped", strings.Repeat("a", 250), 300, strings.Repeat("a", MaxResourceIDLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SafeResourceIDWithLength(tt.input, tt.n); got != tt.want {
+				t.Errorf("SafeResourceIDWithLength(%q, %d) = %q, want %q", tt.input, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"canonical", "//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", nil},
+		{"trailing slash", "//kopexa.com/frameworks/iso27001/", "//kopexa.com/frameworks/iso27001", nil},
+		{"trailing slash before version", "//kopexa.com/frameworks/iso27001/@v1", "//kopexa.com/frameworks/iso27001@v1", nil},
+		{"repeated slashes", "//kopexa.com//frameworks///iso27001//controls/a-5-1", "//kopexa.com/frameworks/iso27001/controls/a-5-1", nil},
+		{"extra leading slashes", "////kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", nil},
+		{"mixed-case service", "//Catalog.Kopexa.COM/frameworks/iso27001", "//catalog.kopexa.com/frameworks/iso27001", nil},
+		{"whitespace", "  //kopexa.com/frameworks/iso27001@v1\n", "//kopexa.com/frameworks/iso27001@v1", nil},
+		{"IDs keep case", "//kopexa.com/frameworks/ISO27001", "//kopexa.com/frameworks/ISO27001", nil},
+		{"version cut at last @", "//kopexa.com/a@b//x/@v1", "//kopexa.com/a@b/x@v1", nil},
+		{"empty", "", "", ErrEmptyKRN},
+		{"whitespace only", "   ", "", ErrEmptyKRN},
+		{"missing prefix", "kopexa.com/frameworks/iso27001", "", ErrInvalidKRN},
+		{"odd path", "//kopexa.com/frameworks/", "", ErrInvalidKRN},
+		{"invalid domain", "//example.com/frameworks/iso27001", "", ErrInvalidDomain},
+		{"invalid resource ID", "//kopexa.com/frameworks/-bad", "", ErrInvalidResourceID},
+		{"invalid version", "//kopexa.com/frameworks/iso27001@-bad", "", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("agrees with Parse", func(t *testing.T) {
+		for _, input := range []string{
+			"//kopexa.com/a@b/x@v1",
+			"//kopexa.com/a@b/x",
+			"//kopexa.com/frameworks/iso27001@v1@v2",
+		} {
+			k, err := Parse(input)
+			got, nerr := Normalize(input)
+			if err != nil || nerr != nil {
+				if err == nil || nerr == nil || nerr.Error() != err.Error() {
+					t.Errorf("Normalize(%q) error %v, Parse error %v", input, nerr, err)
+				}
+				continue
+			}
+			if got != k.String() {
+				t.Errorf("Normalize(%q) = %q, Parse = %q", input, got, k)
+			}
+		}
+	})
+}
+
 func TestGetResource(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -653,6 +1125,88 @@ func TestKRN_String(t *testing.T) {
 	}
 }
 
+func TestKRN_Len(t *testing.T) {
+	inputs := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//catalog.kopexa.com/frameworks/iso27001@v1.2.3",
+		"//isms.kopexa.com/tenants/acme-corp/workspaces/main@latest",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			k := MustParse(input)
+			if got := k.Len(); got != len(k.String()) {
+				t.Errorf("Len() = %d, want %d", got, len(k.String()))
+			}
+		})
+	}
+
+	t.Run("does not allocate", func(t *testing.T) {
+		k := MustParse("//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
+		if allocs := testing.AllocsPerRun(100, func() { _ = k.Len() }); allocs != 0 {
+			t.Errorf("Len() allocated %v times, want 0", allocs)
+		}
+	})
+}
+
+func TestKRN_FitsInBytes(t *testing.T) {
+	k := MustParse("//kopexa.com/frameworks/iso27001") // 32 bytes
+
+	if !k.FitsInBytes(32) {
+		t.Error("expected KRN to fit in its exact length")
+	}
+	if !k.FitsInBytes(100) {
+		t.Error("expected KRN to fit in a larger limit")
+	}
+	if k.FitsInBytes(31) {
+		t.Error("expected KRN not to fit in a smaller limit")
+	}
+}
+
+func TestKRN_TruncateToFit(t *testing.T) {
+	const input = "//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1@v2"
+
+	tests := []struct {
+		name    string
+		limit   int
+		want    string
+		wantErr error
+	}{
+		{name: "already fits", limit: len(input), want: input},
+		{name: "drops version first", limit: len(input) - 1, want: "//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1"},
+		{name: "drops leaf", limit: len(input) - 4, want: "//isms.kopexa.com/tenants/acme-corp/workspaces/main"},
+		{name: "drops to root", limit: 40, want: "//isms.kopexa.com/tenants/acme-corp"},
+		{name: "root exactly fits", limit: len("//isms.kopexa.com/tenants/acme-corp"), want: "//isms.kopexa.com/tenants/acme-corp"},
+		{name: "root too long", limit: 10, wantErr: ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MustParse(input)
+			got, err := k.TruncateToFit(tt.limit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("TruncateToFit(%d) = %q, want %q", tt.limit, got.String(), tt.want)
+			}
+			if got.Len() > tt.limit {
+				t.Errorf("result length %d exceeds limit %d", got.Len(), tt.limit)
+			}
+			if got == k || k.String() != input {
+				t.Error("expected a new KRN and an unchanged original")
+			}
+		})
+	}
+}
+
 func TestKRN_Path(t *testing.T) {
 	tests := []struct {
 		input string
@@ -716,37 +1270,321 @@ func TestKRN_ResourceID(t *testing.T) {
 	})
 }
 
-func TestKRN_MustResourceID(t *testing.T) {
-	k := MustParse("//kopexa.com/frameworks/iso27001")
+func TestKRN_WithResourceID(t *testing.T) {
+	k := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2")
+
+	tests := []struct {
+		name       string
+		collection string
+		newID      string
+		want       string
+		wantErr    error
+	}{
+		{"root", "tenants", "globex", "//isms.kopexa.com/tenants/globex/workspaces/main/evidences/ev-1@v2", nil},
+		{"middle", "workspaces", "dev", "//isms.kopexa.com/tenants/acme/workspaces/dev/evidences/ev-1@v2", nil},
+		{"leaf", "evidences", "ev-2", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-2@v2", nil},
+		{"not found", "controls", "c-1", "", ErrResourceNotFound},
+		{"invalid ID", "tenants", "-bad", "", ErrInvalidResourceID},
+		{"empty ID", "tenants", "", "", ErrInvalidResourceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.WithResourceID(tt.collection, tt.newID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %q, want %q", got.String(), tt.want)
+			}
+			if k.String() != "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2" {
+				t.Errorf("original modified: %q", k.String())
+			}
+		})
+	}
+
+	t.Run("first matching collection", func(t *testing.T) {
+		nested := MustParse("//kopexa.com/folders/a/folders/b")
+		got, err := nested.WithResourceID("folders", "x")
+		if err != nil || got.String() != "//kopexa.com/folders/x/folders/b" {
+			t.Errorf("got (%v, %v)", got, err)
+		}
+	})
+}
+
+func TestKRN_MapSegments(t *testing.T) {
+	k := MustParse("//catalog.kopexa.com/frameworks/ISO27001/controls/A-5-1@v1")
+
+	t.Run("rewrites IDs", func(t *testing.T) {
+		got, err := k.MapSegments(func(seg Segment) Segment {
+			seg.ResourceID = strings.ToLower(seg.ResourceID)
+			return seg
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.String() != "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1" {
+			t.Errorf("got %q", got.String())
+		}
+		if k.String() != "//catalog.kopexa.com/frameworks/ISO27001/controls/A-5-1@v1" {
+			t.Errorf("original modified: %q", k.String())
+		}
+	})
+
+	t.Run("SafeResourceID", func(t *testing.T) {
+		legacy := MustParse("//kopexa.com/frameworks/iso27001")
+		got, err := legacy.MapSegments(func(seg Segment) Segment {
+			seg.ResourceID = SafeResourceID(seg.ResourceID + " (legacy)")
+			return seg
+		})
+		if err != nil || got.String() != "//kopexa.com/frameworks/iso27001--legacy" {
+			t.Errorf("got (%v, %v)", got, err)
+		}
+	})
+
+	t.Run("invalid resource ID", func(t *testing.T) {
+		_, err := k.MapSegments(func(seg Segment) Segment {
+			seg.ResourceID = "-" + seg.ResourceID
+			return seg
+		})
+		if !errors.Is(err, ErrInvalidResourceID) {
+			t.Errorf("expected ErrInvalidResourceID, got %v", err)
+		}
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		_, err := k.MapSegments(func(seg Segment) Segment {
+			return Segment{ResourceID: seg.ResourceID}
+		})
+		if !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+
+	t.Run("unparsable collection", func(t *testing.T) {
+		for _, collection := range []string{"a/b", "a@b", "a?b"} {
+			_, err := k.MapSegments(func(seg Segment) Segment {
+				seg.Collection = collection
+				return seg
+			})
+			if !errors.Is(err, ErrInvalidCollection) || !errors.Is(err, ErrInvalidKRN) {
+				t.Errorf("collection %q: expected ErrInvalidCollection, got %v", collection, err)
+			}
+		}
+	})
+
+	t.Run("keeps the query", func(t *testing.T) {
+		withQuery, _ := ParseWithOptions("//kopexa.com/frameworks/ISO27001@v1?region=eu", WithQuery())
+		got, err := withQuery.MapSegments(func(seg Segment) Segment {
+			seg.ResourceID = strings.ToLower(seg.ResourceID)
+			return seg
+		})
+		if err != nil || got.String() != "//kopexa.com/frameworks/iso27001@v1?region=eu" {
+			t.Errorf("got (%v, %v)", got, err)
+		}
+	})
+}
+
+func TestKRN_MustResourceID(t *testing.T) {
+	k := MustParse("//kopexa.com/frameworks/iso27001")
+
+	t.Run("found", func(t *testing.T) {
+		got := k.MustResourceID("frameworks")
+		if got != "iso27001" {
+			t.Errorf("got %q, want %q", got, "iso27001")
+		}
+	})
+
+	t.Run("not found panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected panic, got none")
+			}
+		}()
+		k.MustResourceID("nonexistent")
+	})
+}
+
+func TestKRN_EnclosingID(t *testing.T) {
+	k := MustParse("//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev-1")
+
+	tests := []struct {
+		collection string
+		want       string
+		wantOK     bool
+	}{
+		{"tenants", "acme-corp", true},
+		{"workspaces", "main", true},
+		{"evidences", "ev-1", true},
+		{"frameworks", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.collection, func(t *testing.T) {
+			got, ok := k.EnclosingID(tt.collection)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("EnclosingID(%q) = (%q, %v), want (%q, %v)", tt.collection, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("first match wins", func(t *testing.T) {
+		k := MustParse("//kopexa.com/controls/a/controls/b")
+		if got, _ := k.EnclosingID("controls"); got != "a" {
+			t.Errorf("EnclosingID() = %q, want %q", got, "a")
+		}
+	})
+}
+
+func TestKRN_HasResource(t *testing.T) {
+	k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+
+	if !k.HasResource("frameworks") {
+		t.Error("expected HasResource(frameworks) to be true")
+	}
+	if !k.HasResource("controls") {
+		t.Error("expected HasResource(controls) to be true")
+	}
+	if k.HasResource("nonexistent") {
+		t.Error("expected HasResource(nonexistent) to be false")
+	}
+}
+
+func TestKRN_Collections(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"//kopexa.com/frameworks/iso27001", []string{"frameworks"}},
+		{"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1", []string{"frameworks", "controls"}},
+		{"//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", []string{"tenants", "workspaces", "evidences"}},
+		{"//kopexa.com/folders/root/folders/child/documents/d-1", []string{"folders", "documents"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := MustParse(tt.input).Collections(); !slices.Equal(got, tt.want) {
+				t.Errorf("Collections() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKRN_HasDuplicateCollections(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"//kopexa.com/frameworks/iso27001", false},
+		{"//kopexa.com/frameworks/iso27001/controls/a-5-1", false},
+		{"//kopexa.com/controls/a/controls/b", true},
+		{"//kopexa.com/tenants/acme/workspaces/main/tenants/acme", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := MustParse(tt.input).HasDuplicateCollections(); got != tt.want {
+				t.Errorf("HasDuplicateCollections() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKRN_ToMap(t *testing.T) {
+	t.Run("unique collections", func(t *testing.T) {
+		got := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v1").ToMap()
+		want := map[string]string{"tenants": "acme", "workspaces": "main", "evidences": "ev-1"}
+		if !maps.Equal(got, want) {
+			t.Errorf("ToMap() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("repeated collection keeps first", func(t *testing.T) {
+		k := MustParse("//kopexa.com/folders/root/folders/child/documents/d-1")
+		got := k.ToMap()
+		want := map[string]string{"folders": "root", "documents": "d-1"}
+		if !maps.Equal(got, want) {
+			t.Errorf("ToMap() = %v, want %v", got, want)
+		}
+		if got["folders"] != k.MustResourceID("folders") {
+			t.Error("ToMap disagrees with ResourceID")
+		}
+	})
+}
+
+func TestKRN_HasSegment(t *testing.T) {
+	k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1@v1")
 
-	t.Run("found", func(t *testing.T) {
-		got := k.MustResourceID("frameworks")
-		if got != "iso27001" {
-			t.Errorf("got %q, want %q", got, "iso27001")
-		}
-	})
+	tests := []struct {
+		collection, resourceID string
+		want                   bool
+	}{
+		{"frameworks", "iso27001", true},
+		{"controls", "a-5-1", true},
+		{"controls", "a-5-2", false},
+		{"frameworks", "a-5-1", false},
+		{"policies", "a-5-1", false},
+	}
 
-	t.Run("not found panics", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Error("expected panic, got none")
-			}
-		}()
-		k.MustResourceID("nonexistent")
-	})
+	for _, tt := range tests {
+		if got := k.HasSegment(tt.collection, tt.resourceID); got != tt.want {
+			t.Errorf("HasSegment(%q, %q) = %v, want %v", tt.collection, tt.resourceID, got, tt.want)
+		}
+	}
 }
 
-func TestKRN_HasResource(t *testing.T) {
-	k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+func TestKRN_Tenant(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		scoped bool
+	}{
+		{"//isms.kopexa.com/tenants/acme-corp/workspaces/main", "acme-corp", true},
+		{"//isms.kopexa.com/tenants/acme-corp", "acme-corp", true},
+		{"//kopexa.com/orgs/o1/tenants/t1/workspaces/w1@v1", "t1", true},
+		{"//kopexa.com/frameworks/iso27001", "", false},
+	}
 
-	if !k.HasResource("frameworks") {
-		t.Error("expected HasResource(frameworks) to be true")
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k := MustParse(tt.input)
+			got, ok := k.Tenant()
+			if got != tt.want || ok != tt.scoped {
+				t.Errorf("Tenant() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.scoped)
+			}
+			if k.IsTenantScoped() != tt.scoped {
+				t.Errorf("IsTenantScoped() = %v, want %v", k.IsTenantScoped(), tt.scoped)
+			}
+		})
 	}
-	if !k.HasResource("controls") {
-		t.Error("expected HasResource(controls) to be true")
+}
+
+func TestKRN_IsService(t *testing.T) {
+	tests := []struct {
+		input   string
+		service string
+		want    bool
+	}{
+		{"//catalog.kopexa.com/frameworks/iso27001", "catalog", true},
+		{"//catalog.kopexa.com/frameworks/iso27001", "isms", false},
+		{"//catalog.kopexa.com/frameworks/iso27001", "", false},
+		{"//kopexa.com/frameworks/iso27001", "", true},
+		{"//kopexa.com/frameworks/iso27001", "catalog", false},
 	}
-	if k.HasResource("nonexistent") {
-		t.Error("expected HasResource(nonexistent) to be false")
+
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.service, func(t *testing.T) {
+			if got := MustParse(tt.input).IsService(tt.service); got != tt.want {
+				t.Errorf("IsService(%q) = %v, want %v", tt.service, got, tt.want)
+			}
+		})
 	}
 }
 
@@ -779,6 +1617,46 @@ func TestKRN_Parent(t *testing.T) {
 	})
 }
 
+func TestKRN_Root(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		isRoot bool
+	}{
+		{"//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1@v1", "//catalog.kopexa.com/frameworks/iso27001", false},
+		{"//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", "//kopexa.com/tenants/acme", false},
+		{"//kopexa.com/frameworks/iso27001@v2", "//kopexa.com/frameworks/iso27001", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k := MustParse(tt.input)
+			if got := k.Root(); got.String() != tt.want {
+				t.Errorf("Root() = %s, want %s", got, tt.want)
+			}
+			if got := k.IsRoot(); got != tt.isRoot {
+				t.Errorf("IsRoot() = %v, want %v", got, tt.isRoot)
+			}
+		})
+	}
+
+	t.Run("independent copy", func(t *testing.T) {
+		k := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+		root := k.Root()
+		root.segments[0].ResourceID = "changed"
+		if k.String() != "//kopexa.com/frameworks/iso27001/controls/a-5-1" {
+			t.Errorf("Root shares segments with the original: %s", k)
+		}
+	})
+
+	t.Run("zero KRN", func(t *testing.T) {
+		var k KRN
+		if k.Root() != nil || k.IsRoot() {
+			t.Error("expected nil root for the zero KRN")
+		}
+	})
+}
+
 func TestKRN_WithVersion(t *testing.T) {
 	k := MustParse("//kopexa.com/frameworks/iso27001")
 
@@ -820,6 +1698,59 @@ func TestKRN_WithoutVersion(t *testing.T) {
 	}
 }
 
+func TestKRN_Clone(t *testing.T) {
+	k := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main@v1")
+	c := k.Clone()
+
+	if c == k {
+		t.Fatal("expected a new KRN")
+	}
+	if !c.Equals(k) {
+		t.Errorf("Clone() = %s, want %s", c, k)
+	}
+
+	c.segments[0].ResourceID = "modified"
+	if k.String() != "//isms.kopexa.com/tenants/acme/workspaces/main@v1" {
+		t.Errorf("original modified: %s", k)
+	}
+
+	var nilKRN *KRN
+	if nilKRN.Clone() != nil {
+		t.Error("expected Clone of nil to be nil")
+	}
+}
+
+func TestKRN_NormalizeParentVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		levels int
+		want   string
+	}{
+		{"leaf keeps version", "//catalog.kopexa.com/frameworks/iso27001@v1", 0, "//catalog.kopexa.com/frameworks/iso27001@v1"},
+		{"negative levels keep version", "//kopexa.com/frameworks/iso27001@v1", -1, "//kopexa.com/frameworks/iso27001@v1"},
+		{"parent drops version", "//catalog.kopexa.com/frameworks/iso27001@v1", 1, "//catalog.kopexa.com/frameworks/iso27001"},
+		{"grandparent drops version", "//kopexa.com/tenants/acme/workspaces/main@v2", 2, "//kopexa.com/tenants/acme/workspaces/main"},
+		{"unversioned unchanged", "//kopexa.com/frameworks/iso27001", 1, "//kopexa.com/frameworks/iso27001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MustParse(tt.input)
+			got := k.NormalizeParentVersion(tt.levels)
+			if got.String() != tt.want {
+				t.Errorf("NormalizeParentVersion(%d) = %q, want %q", tt.levels, got.String(), tt.want)
+			}
+			if got == k {
+				t.Error("expected a new KRN, got the receiver")
+			}
+			if k.String() != tt.input {
+				t.Errorf("original modified: %q", k.String())
+			}
+		})
+	}
+}
+
 func TestKRN_WithService(t *testing.T) {
 	k := MustParse("//kopexa.com/frameworks/iso27001")
 
@@ -860,6 +1791,17 @@ func TestKRN_WithService(t *testing.T) {
 			t.Errorf("got %q, want %q", withService.String(), "//catalog.kopexa.com/frameworks/iso27001@v1")
 		}
 	})
+
+	t.Run("preserves query", func(t *testing.T) {
+		withQuery, _ := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery())
+		withService, err := withQuery.WithService("catalog")
+		if err != nil || withService.String() != "//catalog.kopexa.com/frameworks/iso27001?region=eu" {
+			t.Errorf("got (%v, %v)", withService, err)
+		}
+		if got := withService.WithoutService().String(); got != withQuery.String() {
+			t.Errorf("WithoutService() = %q, want %q", got, withQuery.String())
+		}
+	})
 }
 
 func TestKRN_WithoutService(t *testing.T) {
@@ -924,6 +1866,16 @@ func TestKRN_ServicePreservation(t *testing.T) {
 	})
 }
 
+func TestKRN_FormatVersion(t *testing.T) {
+	k := MustParse("//kopexa.com/frameworks/iso27001")
+	if k.FormatVersion() != FormatVersion {
+		t.Errorf("FormatVersion() = %q, want %q", k.FormatVersion(), FormatVersion)
+	}
+	if FormatVersion != "1" {
+		t.Errorf("FormatVersion = %q, want %q", FormatVersion, "1")
+	}
+}
+
 func TestKRN_Equals(t *testing.T) {
 	k1 := MustParse("//kopexa.com/frameworks/iso27001")
 	k2 := MustParse("//kopexa.com/frameworks/iso27001")
@@ -977,6 +1929,27 @@ func TestKRN_Segments(t *testing.T) {
 	}
 }
 
+func TestSegment_String(t *testing.T) {
+	tests := []struct {
+		seg  Segment
+		want string
+	}{
+		{Segment{Collection: "frameworks", ResourceID: "iso27001"}, "frameworks/iso27001"},
+		{Segment{Collection: "controls", ResourceID: "5.1.1"}, "controls/5.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.seg.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := fmt.Sprint(tt.seg); got != tt.want {
+				t.Errorf("fmt.Sprint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNewChild(t *testing.T) {
 	parent := MustParse("//kopexa.com/frameworks/iso27001")
 
@@ -1023,6 +1996,63 @@ func TestNewChild(t *testing.T) {
 	})
 }
 
+func TestFromSegments(t *testing.T) {
+	segments := []Segment{
+		{Collection: "tenants", ResourceID: "acme"},
+		{Collection: "workspaces", ResourceID: "main"},
+	}
+
+	tests := []struct {
+		name     string
+		service  string
+		segments []Segment
+		version  string
+		want     string
+		wantErr  error
+	}{
+		{"plain", "", segments, "", "//kopexa.com/tenants/acme/workspaces/main", nil},
+		{"service and version", "isms", segments, "v1", "//isms.kopexa.com/tenants/acme/workspaces/main@v1", nil},
+		{"invalid service", "Isms", segments, "", "", ErrInvalidService},
+		{"no segments", "", nil, "", "", ErrInvalidKRN},
+		{"empty collection", "", []Segment{{ResourceID: "acme"}}, "", "", ErrInvalidKRN},
+		{"collection with @", "", []Segment{{Collection: "a@b", ResourceID: "c"}}, "", "", ErrInvalidCollection},
+		{"collection with /", "", []Segment{{Collection: "a/b", ResourceID: "c"}}, "", "", ErrInvalidCollection},
+		{"collection with ?", "", []Segment{{Collection: "a?b", ResourceID: "c"}}, "", "", ErrInvalidCollection},
+		{"invalid resource ID", "", []Segment{{Collection: "tenants", ResourceID: "-acme"}}, "", "", ErrInvalidResourceID},
+		{"invalid version", "", segments, "-v1", "", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromSegments(tt.service, tt.segments, tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("copies segments", func(t *testing.T) {
+		input := []Segment{{Collection: "frameworks", ResourceID: "iso27001"}}
+		k, err := FromSegments("", input, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		input[0].ResourceID = "modified"
+		if k.String() != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("KRN modified through input slice: %q", k.String())
+		}
+	})
+}
+
 func TestNewChildFromString(t *testing.T) {
 	t.Run("valid", func(t *testing.T) {
 		child, err := NewChildFromString("//kopexa.com/frameworks/iso27001", "controls", "a-5-1")
@@ -1042,6 +2072,75 @@ func TestNewChildFromString(t *testing.T) {
 	})
 }
 
+func TestKRN_Append(t *testing.T) {
+	parent := MustParse("//catalog.kopexa.com/frameworks/iso27001@v1")
+
+	child, err := parent.Append("controls", "a-5-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if child.String() != "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1" {
+		t.Errorf("got %q", child.String())
+	}
+	if parent.String() != "//catalog.kopexa.com/frameworks/iso27001@v1" {
+		t.Errorf("Append modified the parent: %s", parent)
+	}
+
+	grandchild, err := child.Append("evidences", "ev-1")
+	if err != nil || grandchild.Depth() != 3 {
+		t.Errorf("chained Append = (%v, %v)", grandchild, err)
+	}
+
+	if _, err := parent.Append("", "a-5-1"); !errors.Is(err, ErrInvalidKRN) {
+		t.Errorf("expected ErrInvalidKRN, got %v", err)
+	}
+	if _, err := parent.Append("controls", "-bad"); !errors.Is(err, ErrInvalidResourceID) {
+		t.Errorf("expected ErrInvalidResourceID, got %v", err)
+	}
+}
+
+func TestKRN_Children(t *testing.T) {
+	parent := MustParse("//isms.kopexa.com/tenants/acme/control-implementations/ci-1@v1")
+
+	children, err := parent.Children("evidences", []string{"ev-1", "ev-2", "ev-3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"//isms.kopexa.com/tenants/acme/control-implementations/ci-1/evidences/ev-1",
+		"//isms.kopexa.com/tenants/acme/control-implementations/ci-1/evidences/ev-2",
+		"//isms.kopexa.com/tenants/acme/control-implementations/ci-1/evidences/ev-3",
+	}
+	if len(children) != len(want) {
+		t.Fatalf("got %d children, want %d", len(children), len(want))
+	}
+	for i, child := range children {
+		if child.String() != want[i] {
+			t.Errorf("children[%d] = %s, want %s", i, child, want[i])
+		}
+	}
+
+	if got, err := parent.Children("evidences", nil); err != nil || len(got) != 0 {
+		t.Errorf("Children(nil) = (%v, %v), want empty", got, err)
+	}
+
+	t.Run("fails fast", func(t *testing.T) {
+		got, err := parent.Children("evidences", []string{"ev-1", "-bad", ""})
+		if got != nil || !errors.Is(err, ErrInvalidResourceID) {
+			t.Fatalf("expected ErrInvalidResourceID, got (%v, %v)", got, err)
+		}
+		if !strings.HasPrefix(err.Error(), `id 1 ("-bad")`) {
+			t.Errorf("expected error to name the offending id, got %v", err)
+		}
+	})
+
+	t.Run("empty collection", func(t *testing.T) {
+		if _, err := parent.Children("", []string{"ev-1"}); !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+}
+
 func TestBuilder(t *testing.T) {
 	t.Run("simple build", func(t *testing.T) {
 		k, err := New().
@@ -1156,7 +2255,7 @@ func TestBuilder(t *testing.T) {
 		}
 	})
 
-	t.Run("error propagation stops further operations", func(t *testing.T) {
+	t.Run("later valid calls keep the error", func(t *testing.T) {
 		_, err := New().
 			Resource("", "iso27001").  // Error here
 			Resource("controls", "a"). // Should not panic
@@ -1166,6 +2265,117 @@ func TestBuilder(t *testing.T) {
 			t.Errorf("expected ErrInvalidKRN, got %v", err)
 		}
 	})
+
+	t.Run("collects all errors", func(t *testing.T) {
+		b := New().
+			Service("Invalid").
+			Resource("frameworks", "iso27001").
+			Resource("", "a").
+			Resource("controls", "-bad").
+			Version("-invalid")
+
+		want := []error{ErrInvalidService, ErrInvalidKRN, ErrInvalidResourceID, ErrInvalidVersion}
+		errs := b.Errors()
+		if len(errs) != len(want) {
+			t.Fatalf("Errors() = %v, want %d errors", errs, len(want))
+		}
+		for i, err := range errs {
+			if !errors.Is(err, want[i]) {
+				t.Errorf("error %d: expected %v, got %v", i, want[i], err)
+			}
+		}
+
+		k, err := b.Build()
+		if k != nil {
+			t.Errorf("expected nil KRN, got %s", k)
+		}
+		for _, sentinel := range want {
+			if !errors.Is(err, sentinel) {
+				t.Errorf("Build() error %v does not match %v", err, sentinel)
+			}
+		}
+
+		errs[0] = nil
+		if b.Errors()[0] == nil {
+			t.Error("Errors() returned the builder's internal slice")
+		}
+	})
+
+	t.Run("no errors", func(t *testing.T) {
+		if errs := New().Resource("frameworks", "iso27001").Errors(); errs != nil {
+			t.Errorf("expected nil, got %v", errs)
+		}
+	})
+}
+
+func TestNewFrom(t *testing.T) {
+	t.Run("extends parsed KRN", func(t *testing.T) {
+		parent := MustParse("//catalog.kopexa.com/frameworks/iso27001")
+		k, err := NewFrom(parent).
+			Resource("controls", "a-5-1").
+			Build()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if k.String() != "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1" {
+			t.Errorf("got %q", k.String())
+		}
+	})
+
+	t.Run("drops version", func(t *testing.T) {
+		parent := MustParse("//kopexa.com/frameworks/iso27001@v2")
+		k := NewFrom(parent).Resource("controls", "a-5-1").MustBuild()
+		if k.HasVersion() {
+			t.Errorf("expected no version, got %q", k.Version())
+		}
+
+		rebuilt := NewFrom(parent).MustBuild()
+		if rebuilt.String() != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("got %q", rebuilt.String())
+		}
+	})
+
+	t.Run("version can be set again", func(t *testing.T) {
+		parent := MustParse("//kopexa.com/frameworks/iso27001@v2")
+		k := NewFrom(parent).Version("v3").MustBuild()
+		if k.String() != "//kopexa.com/frameworks/iso27001@v3" {
+			t.Errorf("got %q", k.String())
+		}
+	})
+
+	t.Run("does not alias source", func(t *testing.T) {
+		parent := MustParse("//kopexa.com/tenants/acme/workspaces/main")
+		k := NewFrom(parent).Resource("evidences", "ev-1").MustBuild()
+		k.segments[0].ResourceID = "modified"
+		if parent.String() != "//kopexa.com/tenants/acme/workspaces/main" {
+			t.Errorf("source modified: %q", parent.String())
+		}
+	})
+
+	t.Run("nil source", func(t *testing.T) {
+		_, err := NewFrom(nil).Resource("frameworks", "iso27001").Build()
+		if !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+}
+
+func TestFrom(t *testing.T) {
+	parent := MustParse("//isms.kopexa.com/tenants/acme@v1")
+	k, err := From(parent).
+		Resource("workspaces", "main").
+		Resource("evidences", "ev-1").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.String() != "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1" {
+		t.Errorf("got %q", k.String())
+	}
+
+	if _, err := From(nil).Build(); !errors.Is(err, ErrInvalidKRN) {
+		t.Errorf("expected ErrInvalidKRN, got %v", err)
+	}
 }
 
 func TestBuilder_MustBuild(t *testing.T) {
@@ -1188,6 +2398,29 @@ func TestBuilder_MustBuild(t *testing.T) {
 	})
 }
 
+func TestKRN_ResourceType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ResourceType
+	}{
+		{"//kopexa.com/frameworks/iso27001", ResourceTypeFrameworks},
+		{"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1", ResourceTypeControls},
+		{"//isms.kopexa.com/tenants/acme", ResourceTypeTenants},
+		{"//isms.kopexa.com/tenants/acme/workspaces/main", ResourceTypeWorkspaces},
+		{"//isms.kopexa.com/tenants/acme/control-implementations/ci-1", ResourceTypeControlImplementations},
+		{"//isms.kopexa.com/tenants/acme/control-implementations/ci-1/evidences/ev-1", ResourceTypeEvidences},
+		{"//kopexa.com/policies/p-1", ResourceType("policies")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := MustParse(tt.input).ResourceType(); got != tt.want {
+				t.Errorf("ResourceType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestKRN_EmptySegments(t *testing.T) {
 	// Test the edge case where a KRN is created with zero segments
 	// This shouldn't happen via Parse, but test the methods handle it gracefully
@@ -1201,6 +2434,77 @@ func TestKRN_EmptySegments(t *testing.T) {
 	if k.BasenameCollection() != "" {
 		t.Errorf("expected empty basename collection, got %q", k.BasenameCollection())
 	}
+	if k.ResourceType() != "" {
+		t.Errorf("expected empty resource type, got %q", k.ResourceType())
+	}
+}
+
+// Fuzzing
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		// Valid KRNs
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//catalog.kopexa.com/frameworks/iso27001@v1.2.3",
+		"//isms.kopexa.com/tenants/acme-corp/workspaces/main/evidences/ev_1@latest",
+		"//xn--bcher-kva.kopexa.com/frameworks/iso27001@draft",
+		"//kopexa.com/frameworks/nist-csf-2.0/controls/GV.OC-01@2022-01-15",
+		"//kopexa.com/" + strings.Repeat("a/b/", 50) + "c/d",
+
+		// Malformed input
+		"",
+		"//",
+		"///",
+		"//kopexa.com",
+		"//kopexa.com/",
+		"//kopexa.com//",
+		"//kopexa.com/frameworks",
+		"//kopexa.com/frameworks/iso27001@",
+		"//kopexa.com/frameworks/iso27001@v",
+		"//kopexa.com/frameworks/iso27001@@v1",
+		"//kopexa.com/frameworks/iso@27001@v1",
+		"//.kopexa.com/frameworks/iso27001",
+		"//-svc.kopexa.com/frameworks/iso27001",
+		"//kopexa.com.evil.com/frameworks/iso27001",
+		"kopexa.com/frameworks/iso27001",
+
+		// Unicode
+		"//kopexa.com/frameworks/\u0430cme",
+		"//bücher.kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001@v1\x00",
+		"//kopexa.com/\xff\xfe/id",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		k, err := Parse(s)
+		if err != nil {
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Parse(%q) returned %T, want *ParseError", s, err)
+			}
+			return
+		}
+
+		out := k.String()
+		if out != s {
+			t.Fatalf("Parse(%q).String() = %q", s, out)
+		}
+		if k.Len() != len(out) {
+			t.Fatalf("Len() = %d, want %d", k.Len(), len(out))
+		}
+
+		again, err := Parse(out)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed on round trip: %v", out, err)
+		}
+		if !again.Equals(k) {
+			t.Fatalf("round trip mismatch: %q != %q", again, k)
+		}
+	})
 }
 
 // Benchmarks
@@ -1278,5 +2582,10 @@ func BenchmarkIsValidResourceID(b *testing.B) {
 				_ = IsValidResourceID(id)
 			}
 		})
+		b.Run(id[:min(len(id), 20)]+"/regexp", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = resourceIDRegexp.MatchString(id)
+			}
+		})
 	}
 }
@@ -0,0 +1,118 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import "strings"
+
+// FixupCode identifies the kind of correction made by ParseLenient.
+type FixupCode string
+
+// Fixup codes reported by ParseLenient.
+const (
+	// FixMissingPrefix flags input without the leading "//", e.g.
+	// "kopexa.com/frameworks/iso27001".
+	FixMissingPrefix FixupCode = "missing-prefix"
+
+	// FixUppercaseHost flags a host with uppercase letters, e.g.
+	// "//Catalog.kopexa.com/...". The host is lowercased.
+	FixUppercaseHost FixupCode = "uppercase-host"
+
+	// FixUnsafeResourceID flags an invalid resource ID that was replaced by its
+	// SafeResourceID form, e.g. "Acme Corp" by "Acme-Corp".
+	FixUnsafeResourceID FixupCode = "unsafe-resource-id"
+
+	// FixEncodedResourceID flags a resource ID containing "@" that was replaced
+	// by its EncodeResourceID form, e.g. "jane@example.com" by
+	// "x--jane_40example.com", instead of being read as a version.
+	FixEncodedResourceID FixupCode = "encoded-resource-id"
+)
+
+// Fixup describes one correction made by ParseLenient.
+type Fixup struct {
+	Code     FixupCode
+	Original string // the input text before the correction
+	Fixed    string // the text after the correction
+	// Segment is the index of the corrected segment, or -1 if the fixup
+	// concerns the prefix or host.
+	Segment int
+}
+
+// ParseLenient parses user input such as a search box query, correcting common
+// mistakes instead of rejecting them: a missing "//" prefix is added, the host
+// is lowercased and invalid resource IDs are replaced by their SafeResourceID
+// form. It returns the parsed KRN and the corrections made, in input order, so
+// callers can ask "did you mean k?". Fixups is nil if the input was already
+// valid.
+//
+// The text after the last "@" is only taken as the version if it looks like one:
+// a registered version channel such as "latest", or a version starting with a
+// digit or with "v" and a digit. Otherwise, as in "users/jane@example.com", the
+// "@" belongs to a resource ID, which is replaced by its EncodeResourceID form
+// so that it can be recovered with DecodeResourceID.
+//
+// Input that cannot be corrected returns the Parse error of the corrected
+// string. Parse itself stays strict.
+func ParseLenient(s string) (*KRN, []Fixup, error) {
+	if s == "" {
+		_, err := Parse(s)
+		return nil, nil, err
+	}
+
+	var fixups []Fixup
+	if !strings.HasPrefix(s, "//") {
+		fixed := "//" + strings.TrimPrefix(s, "/")
+		fixups = append(fixups, Fixup{Code: FixMissingPrefix, Original: s, Fixed: fixed, Segment: -1})
+		s = fixed
+	}
+
+	body, version := s[2:], ""
+	if idx := strings.LastIndex(body, "@"); idx != -1 && looksLikeVersion(body[idx+1:]) {
+		body, version = body[:idx], body[idx:]
+	}
+
+	parts := strings.Split(body, "/")
+	if host := strings.ToLower(parts[0]); host != parts[0] {
+		fixups = append(fixups, Fixup{Code: FixUppercaseHost, Original: parts[0], Fixed: host, Segment: -1})
+		parts[0] = host
+	}
+
+	for i := 2; i < len(parts); i += 2 {
+		id := parts[i]
+		if IsValidResourceID(id) {
+			continue
+		}
+		code, fixed := FixUnsafeResourceID, SafeResourceID(id)
+		if strings.Contains(id, "@") {
+			// SafeResourceID would drop the "@", e.g. of an e-mail address.
+			code, fixed = FixEncodedResourceID, EncodeResourceID(id)
+		}
+		if !IsValidResourceID(fixed) {
+			// Nothing to suggest; Parse reports the error.
+			continue
+		}
+		fixups = append(fixups, Fixup{Code: code, Original: id, Fixed: fixed, Segment: i/2 - 1})
+		parts[i] = fixed
+	}
+
+	k, err := Parse("//" + strings.Join(parts, "/") + version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, fixups, nil
+}
+
+// looksLikeVersion reports whether ParseLenient takes the text v after the last
+// "@" as a version. Empty text counts as a version, so that Parse reports it.
+func looksLikeVersion(v string) bool {
+	if v == "" || isFloatingVersion(v) {
+		return true
+	}
+	if strings.Contains(v, "/") {
+		return false
+	}
+	if len(v) > 1 && (v[0] == 'v' || v[0] == 'V') {
+		v = v[1:]
+	}
+	return '0' <= v[0] && v[0] <= '9'
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		fixups  []Fixup
+		wantErr error
+	}{
+		{
+			name:  "valid input",
+			input: "//catalog.kopexa.com/frameworks/iso27001@v1",
+			want:  "//catalog.kopexa.com/frameworks/iso27001@v1",
+		},
+		{
+			name:   "missing prefix",
+			input:  "kopexa.com/frameworks/iso27001",
+			want:   "//kopexa.com/frameworks/iso27001",
+			fixups: []Fixup{{Code: FixMissingPrefix, Original: "kopexa.com/frameworks/iso27001", Fixed: "//kopexa.com/frameworks/iso27001", Segment: -1}},
+		},
+		{
+			name:   "single slash prefix",
+			input:  "/kopexa.com/frameworks/iso27001",
+			want:   "//kopexa.com/frameworks/iso27001",
+			fixups: []Fixup{{Code: FixMissingPrefix, Original: "/kopexa.com/frameworks/iso27001", Fixed: "//kopexa.com/frameworks/iso27001", Segment: -1}},
+		},
+		{
+			name:   "uppercase service",
+			input:  "//Catalog.kopexa.com/frameworks/iso27001",
+			want:   "//catalog.kopexa.com/frameworks/iso27001",
+			fixups: []Fixup{{Code: FixUppercaseHost, Original: "Catalog.kopexa.com", Fixed: "catalog.kopexa.com", Segment: -1}},
+		},
+		{
+			name:   "unsafe resource ID",
+			input:  "//kopexa.com/tenants/Acme Corp!/workspaces/main@v1",
+			want:   "//kopexa.com/tenants/Acme-Corp/workspaces/main@v1",
+			fixups: []Fixup{{Code: FixUnsafeResourceID, Original: "Acme Corp!", Fixed: "Acme-Corp", Segment: 0}},
+		},
+		{
+			name:  "all corrections in input order",
+			input: "ISMS.kopexa.com/tenants/acme/workspaces/-main-",
+			want:  "//isms.kopexa.com/tenants/acme/workspaces/main",
+			fixups: []Fixup{
+				{Code: FixMissingPrefix, Original: "ISMS.kopexa.com/tenants/acme/workspaces/-main-", Fixed: "//ISMS.kopexa.com/tenants/acme/workspaces/-main-", Segment: -1},
+				{Code: FixUppercaseHost, Original: "ISMS.kopexa.com", Fixed: "isms.kopexa.com", Segment: -1},
+				{Code: FixUnsafeResourceID, Original: "-main-", Fixed: "main", Segment: 1},
+			},
+		},
+		{
+			name:  "e-mail address as resource ID",
+			input: "kopexa.com/users/jane@example.com",
+			want:  "//kopexa.com/users/x--jane_40example.com",
+			fixups: []Fixup{
+				{Code: FixMissingPrefix, Original: "kopexa.com/users/jane@example.com", Fixed: "//kopexa.com/users/jane@example.com", Segment: -1},
+				{Code: FixEncodedResourceID, Original: "jane@example.com", Fixed: "x--jane_40example.com", Segment: 0},
+			},
+		},
+		{
+			name:   "@ followed by more path",
+			input:  "//kopexa.com/users/jane@1/roles/admin",
+			want:   "//kopexa.com/users/x--jane_401/roles/admin",
+			fixups: []Fixup{{Code: FixEncodedResourceID, Original: "jane@1", Fixed: "x--jane_401", Segment: 0}},
+		},
+		{
+			name:  "version channel",
+			input: "//kopexa.com/frameworks/iso27001@latest",
+			want:  "//kopexa.com/frameworks/iso27001@latest",
+		},
+		{
+			name:  "date version",
+			input: "//kopexa.com/frameworks/iso27001@2022-01",
+			want:  "//kopexa.com/frameworks/iso27001@2022-01",
+		},
+		{name: "empty", input: "", wantErr: ErrEmptyKRN},
+		{name: "uncorrectable resource ID", input: "//kopexa.com/tenants/!!!", wantErr: ErrInvalidResourceID},
+		{name: "wrong domain", input: "example.com/tenants/acme", wantErr: ErrInvalidDomain},
+		{name: "odd path", input: "//kopexa.com/tenants", wantErr: ErrInvalidKRN},
+		{name: "invalid version", input: "//kopexa.com/tenants/acme@v1!", wantErr: ErrInvalidVersion},
+		{name: "empty version", input: "//kopexa.com/tenants/acme@", wantErr: ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, fixups, err := ParseLenient(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || k != nil || fixups != nil {
+					t.Errorf("expected %v, got %v, %v, %v", tt.wantErr, k, fixups, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.String() != tt.want {
+				t.Errorf("ParseLenient() = %s, want %s", k, tt.want)
+			}
+			if !reflect.DeepEqual(fixups, tt.fixups) {
+				t.Errorf("fixups = %+v, want %+v", fixups, tt.fixups)
+			}
+		})
+	}
+}
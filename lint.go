@@ -0,0 +1,75 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WarningCode identifies the kind of style issue reported by Lint.
+type WarningCode string
+
+// Warning codes reported by Lint.
+const (
+	// WarnMixedSeparators flags resource IDs that use both "." and "-" as word
+	// separators, e.g. "a.5-1". Pick one separator per ID.
+	WarnMixedSeparators WarningCode = "mixed-separators"
+
+	// WarnUppercaseID flags resource IDs containing uppercase letters. IDs are
+	// expected to be lowercase slugs, e.g. "acme-corp" rather than "Acme-Corp".
+	WarnUppercaseID WarningCode = "uppercase-id"
+
+	// WarnVersionPrefix flags semantic versions written without the "v" prefix,
+	// e.g. "1.2.0" instead of "v1.2.0". Keywords and date versions are not flagged.
+	WarnVersionPrefix WarningCode = "version-prefix"
+)
+
+// Warning is a non-fatal style advisory reported by Lint.
+type Warning struct {
+	Code    WarningCode
+	Message string
+	// Segment is the index of the offending segment, or -1 if the warning
+	// concerns the version.
+	Segment int
+}
+
+// Lint reports stylistic issues in a valid KRN. Warnings do not affect
+// validity: Parse accepts every KRN that Lint may warn about.
+// Warnings are ordered by segment, with version warnings last.
+// Returns nil if there is nothing to report or k is nil.
+func Lint(k *KRN) []Warning {
+	if k == nil {
+		return nil
+	}
+
+	var warnings []Warning
+	for i, seg := range k.segments {
+		id := seg.ResourceID
+		if strings.Contains(id, ".") && strings.Contains(id, "-") {
+			warnings = append(warnings, Warning{
+				Code:    WarnMixedSeparators,
+				Message: fmt.Sprintf("resource ID %q mixes \".\" and \"-\" separators", id),
+				Segment: i,
+			})
+		}
+		if strings.ToLower(id) != id {
+			warnings = append(warnings, Warning{
+				Code:    WarnUppercaseID,
+				Message: fmt.Sprintf("resource ID %q contains uppercase letters", id),
+				Segment: i,
+			})
+		}
+	}
+
+	if _, ok := parseSemver(k.version); ok && !strings.HasPrefix(k.version, "v") {
+		warnings = append(warnings, Warning{
+			Code:    WarnVersionPrefix,
+			Message: fmt.Sprintf("version %q should use the \"v\" prefix (v%s)", k.version, k.version),
+			Segment: -1,
+		})
+	}
+
+	return warnings
+}
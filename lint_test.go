@@ -0,0 +1,58 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Warning
+	}{
+		{"clean", "//kopexa.com/frameworks/iso27001/controls/a-5-1@v1.2.0", nil},
+		{"clean dotted ID", "//kopexa.com/frameworks/iso27001/controls/a.5.1", nil},
+		{"keyword version", "//kopexa.com/frameworks/iso27001@latest", nil},
+		{"date version", "//kopexa.com/frameworks/iso27001@2022-01-15", nil},
+		{"mixed separators", "//kopexa.com/frameworks/iso27001/controls/a.5-1", []Warning{
+			{Code: WarnMixedSeparators, Segment: 1},
+		}},
+		{"uppercase ID", "//isms.kopexa.com/tenants/Acme-Corp/workspaces/main", []Warning{
+			{Code: WarnUppercaseID, Segment: 0},
+		}},
+		{"version without prefix", "//kopexa.com/frameworks/iso27001@1.2.0", []Warning{
+			{Code: WarnVersionPrefix, Segment: -1},
+		}},
+		{"multiple", "//kopexa.com/frameworks/ISO.27001-2022/controls/A-5-1@2", []Warning{
+			{Code: WarnMixedSeparators, Segment: 0},
+			{Code: WarnUppercaseID, Segment: 0},
+			{Code: WarnUppercaseID, Segment: 1},
+			{Code: WarnVersionPrefix, Segment: -1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MustParse(tt.input)
+			got := Lint(k)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lint() returned %d warnings, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				if got[i].Code != w.Code || got[i].Segment != w.Segment {
+					t.Errorf("warning %d = {%s %d}, want {%s %d}", i, got[i].Code, got[i].Segment, w.Code, w.Segment)
+				}
+				if got[i].Message == "" {
+					t.Errorf("warning %d has no message", i)
+				}
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if got := Lint(nil); got != nil {
+			t.Errorf("Lint(nil) = %v, want nil", got)
+		}
+	})
+}
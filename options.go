@@ -0,0 +1,211 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Option configures ParseWithOptions.
+type Option func(*parseOptions)
+
+type parseOptions struct {
+	domain              string
+	strictCollections   bool
+	decodeResourceIDs   bool
+	versionPolicy       versionPolicy
+	maxDepth            int // 0 means unbounded
+	maxResourceIDLength int // 0 means MaxResourceIDLength
+	allowQuery          bool
+	uniqueCollections   bool
+}
+
+// versionPolicy controls whether parsed KRNs must or must not have a version.
+type versionPolicy int
+
+const (
+	versionOptional versionPolicy = iota
+	versionRequired
+	versionForbidden
+)
+
+// WithDomain sets the base domain accepted by ParseWithOptions, e.g.
+// "kopexa.dev" for staging or a customer's own domain for on-prem installations.
+// The host must then be "{domain}" or "{service}.{domain}". The bare domain
+// "kopexa" is reserved, and subdomains of Domain such as "eu.kopexa.com" would
+// be ambiguous with services; both return ErrInvalidDomain. KRNs parsed this way
+// keep their domain in String(), FullDomain() and all derived KRNs. The text
+// decoders (UnmarshalJSON, Scan, FromURL, ...) only read them back once the
+// domain is registered with RegisterDomain.
+func WithDomain(domain string) Option {
+	return func(o *parseOptions) {
+		o.domain = domain
+	}
+}
+
+// WithStrictCollections makes ParseWithOptions reject collection names that
+// fail IsValidCollection (e.g. uppercase letters or spaces) with
+// ErrInvalidCollection. Parse accepts any non-empty collection for backward
+// compatibility.
+func WithStrictCollections() Option {
+	return func(o *parseOptions) {
+		o.strictCollections = true
+	}
+}
+
+// WithDecoding makes ParseWithOptions accept percent-encoded resource IDs, e.g.
+// "jane%40example.com" as copied from a URL. Each resource ID containing "%"
+// is percent-decoded and stored in its EncodeResourceID form, so the parsed
+// KRN stays canonical: "acme%2Dcorp" is stored as "acme-corp", while
+// "jane%40example.com" is stored as "x--jane_40example.com" and
+// DecodeResourceID returns the original identifier. Resource IDs without "%"
+// are taken as-is. A malformed escape, or a decoded ID that cannot be encoded
+// as a valid resource ID, returns ErrInvalidResourceID.
+func WithDecoding() Option {
+	return func(o *parseOptions) {
+		o.decodeResourceIDs = true
+	}
+}
+
+// WithRequireVersion makes ParseWithOptions reject unversioned KRNs with
+// ErrVersionRequired, e.g. for APIs that only accept pinned references.
+// It overrides an earlier WithForbidVersion.
+func WithRequireVersion() Option {
+	return func(o *parseOptions) {
+		o.versionPolicy = versionRequired
+	}
+}
+
+// WithForbidVersion makes ParseWithOptions reject versioned KRNs with
+// ErrVersionForbidden, e.g. for APIs that only accept mutable references.
+// It overrides an earlier WithRequireVersion.
+func WithForbidVersion() Option {
+	return func(o *parseOptions) {
+		o.versionPolicy = versionForbidden
+	}
+}
+
+// WithMaxDepth makes ParseWithOptions reject KRNs with more than n segments
+// with ErrInvalidKRN, e.g. to protect APIs from pathologically deep input. The
+// depth is checked before the path is split, so rejected input does not
+// allocate its segments. By default, and for n <= 0, depth is unbounded.
+func WithMaxDepth(n int) Option {
+	return func(o *parseOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxResourceIDLength makes ParseWithOptions reject resource IDs longer
+// than n bytes with ErrInvalidResourceID, e.g. when IDs are stored in a
+// 64-character database column. The limit can only be lowered: resource IDs
+// longer than MaxResourceIDLength are always invalid. n <= 0 keeps the default.
+func WithMaxResourceIDLength(n int) Option {
+	return func(o *parseOptions) {
+		o.maxResourceIDLength = n
+	}
+}
+
+// WithUniqueCollections makes ParseWithOptions reject KRNs in which a
+// collection name appears more than once, e.g. //kopexa.com/controls/a/controls/b,
+// with ErrInvalidKRN. See HasDuplicateCollections.
+func WithUniqueCollections() Option {
+	return func(o *parseOptions) {
+		o.uniqueCollections = true
+	}
+}
+
+// WithQuery makes ParseWithOptions accept a trailing query string with
+// attributes that are not part of the hierarchy, such as a region or revision:
+// //kopexa.com/frameworks/iso27001@v1?region=eu. The query follows the version,
+// if any, and is available via Query. It is kept in canonical form (keys
+// sorted, values escaped) by String, so it takes part in Equals, hashes and
+// every encoding. The decoders (UnmarshalJSON, UnmarshalText, UnmarshalYAML,
+// UnmarshalBinary, Scan, FromURL and ParseLogToken) always accept the query.
+// Clone, WithService, WithoutService, RewriteService and MapSegments keep the
+// query; other derived KRNs (Parent, WithVersion, ...) drop it.
+// Without this option a "?" remains invalid.
+func WithQuery() Option {
+	return func(o *parseOptions) {
+		o.allowQuery = true
+	}
+}
+
+// ParseWithOptions parses a KRN string like Parse, configured by opts.
+// Without options it behaves exactly like Parse. An invalid domain passed to
+// WithDomain returns ErrInvalidDomain.
+func ParseWithOptions(s string, opts ...Option) (*KRN, error) {
+	o := parseOptions{domain: Domain}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.domain != Domain && !isValidDomain(o.domain) {
+		return nil, fmt.Errorf("%w: invalid base domain %q", ErrInvalidDomain, o.domain)
+	}
+	return parseKRN(s, o)
+}
+
+// isValidDomain reports whether domain is a dot-separated list of valid labels
+// that can be told apart from the default Domain. Labels follow the same rules
+// as service names. The bare domain "kopexa" is reserved as the ARNLike
+// partition of Domain, and Domain itself, its subdomains and its parents are
+// rejected, since their hosts would be ambiguous with services of Domain.
+func isValidDomain(domain string) bool {
+	if domain == "" || domain == arnPartition || domainsOverlap(domain, Domain) {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if !IsValidService(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeResourceID percent-decodes raw and returns it in EncodeResourceID form.
+func decodeResourceID(raw string) (string, error) {
+	decoded, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrInvalidResourceID, raw, err)
+	}
+
+	id := EncodeResourceID(decoded)
+	if !IsValidResourceID(id) {
+		return "", fmt.Errorf("%w: %s cannot be encoded as a resource ID", ErrInvalidResourceID, raw)
+	}
+	return id, nil
+}
+
+// checkVersionPolicy returns a *ParseError if the presence of version in input
+// violates the version policy. end is the byte offset where the version, if
+// any, ends in input.
+func (o parseOptions) checkVersionPolicy(input, version string, end int) error {
+	switch {
+	case o.versionPolicy == versionRequired && version == "":
+		return &ParseError{Input: input, Offset: end, Segment: -1, Err: ErrVersionRequired}
+	case o.versionPolicy == versionForbidden && version != "":
+		return &ParseError{Input: input, Value: version, Offset: end - len(version), Segment: -1, Err: ErrVersionForbidden}
+	}
+	return nil
+}
+
+// cutQuery splits body, the part of input after "//", at the first "?" and
+// returns the part before it and the query in canonical url.Values encoding
+// (keys sorted). An empty query is dropped. A malformed query returns a
+// *ParseError wrapping ErrInvalidKRN.
+func cutQuery(input, body string) (string, string, error) {
+	idx := strings.IndexByte(body, '?')
+	if idx == -1 {
+		return body, "", nil
+	}
+
+	raw := body[idx+1:]
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return "", "", &ParseError{Input: input, Value: raw, Offset: 2 + idx + 1, Segment: -1, Err: fmt.Errorf("%w: invalid query: %w", ErrInvalidKRN, err)}
+	}
+	return body[:idx], q.Encode(), nil
+}
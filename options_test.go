@@ -0,0 +1,512 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []Option
+		service string
+		wantErr error
+	}{
+		{"default domain", "//kopexa.com/frameworks/iso27001", nil, "", nil},
+		{"default with service", "//catalog.kopexa.com/frameworks/iso27001", nil, "catalog", nil},
+		{"default rejects other domain", "//kopexa.dev/frameworks/iso27001", nil, "", ErrInvalidDomain},
+		{"custom domain", "//kopexa.dev/frameworks/iso27001@v1", []Option{WithDomain("kopexa.dev")}, "", nil},
+		{"custom domain with service", "//isms.acme-corp.example/tenants/acme", []Option{WithDomain("acme-corp.example")}, "isms", nil},
+		{"custom domain rejects default", "//kopexa.com/frameworks/iso27001", []Option{WithDomain("kopexa.dev")}, "", ErrInvalidDomain},
+		{"custom domain invalid service", "//Isms.kopexa.dev/tenants/acme", []Option{WithDomain("kopexa.dev")}, "", ErrInvalidService},
+		{"custom domain invalid path", "//kopexa.dev/frameworks", []Option{WithDomain("kopexa.dev")}, "", ErrInvalidKRN},
+		{"explicit default domain", "//kopexa.com/frameworks/iso27001", []Option{WithDomain(Domain)}, "", nil},
+		{"last option wins", "//kopexa.dev/frameworks/iso27001", []Option{WithDomain("kopexa.io"), WithDomain("kopexa.dev")}, "", nil},
+		{"empty domain", "//kopexa.com/frameworks/iso27001", []Option{WithDomain("")}, "", ErrInvalidDomain},
+		{"invalid domain", "//kopexa.com/frameworks/iso27001", []Option{WithDomain("Kopexa.dev")}, "", ErrInvalidDomain},
+		{"empty domain label", "//kopexa.com/frameworks/iso27001", []Option{WithDomain("kopexa..dev")}, "", ErrInvalidDomain},
+		{"subdomain of default", "//isms.eu.kopexa.com/tenants/acme", []Option{WithDomain("eu.kopexa.com")}, "", ErrInvalidDomain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseWithOptions(tt.input, tt.opts...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.String() != tt.input {
+				t.Errorf("String() = %q, want %q", k.String(), tt.input)
+			}
+			if k.Len() != len(tt.input) {
+				t.Errorf("Len() = %d, want %d", k.Len(), len(tt.input))
+			}
+			if k.Service() != tt.service {
+				t.Errorf("Service() = %q, want %q", k.Service(), tt.service)
+			}
+		})
+	}
+}
+
+func TestWithDomain_DerivedKRNs(t *testing.T) {
+	k, err := ParseWithOptions("//isms.kopexa.dev/tenants/acme/workspaces/main@v1", WithDomain("kopexa.dev"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := k.FullDomain(); got != "isms.kopexa.dev" {
+		t.Errorf("FullDomain() = %q", got)
+	}
+
+	child, err := NewChild(k, "evidences", "ev-1")
+	if err != nil {
+		t.Fatalf("NewChild() error: %v", err)
+	}
+	built := NewFrom(k).Resource("evidences", "ev-1").MustBuild()
+	relative, err := ResolveRelative("evidences/ev-1", k)
+	if err != nil {
+		t.Fatalf("ResolveRelative() error: %v", err)
+	}
+
+	derived := map[string]*KRN{
+		"Parent":         k.Parent(),
+		"WithoutVersion": k.WithoutVersion(),
+		"WithoutService": k.WithoutService(),
+		"NewChild":       child,
+		"NewFrom":        built,
+		"Resolve":        relative,
+	}
+	want := map[string]string{
+		"Parent":         "//isms.kopexa.dev/tenants/acme",
+		"WithoutVersion": "//isms.kopexa.dev/tenants/acme/workspaces/main",
+		"WithoutService": "//kopexa.dev/tenants/acme/workspaces/main@v1",
+		"NewChild":       "//isms.kopexa.dev/tenants/acme/workspaces/main/evidences/ev-1",
+		"NewFrom":        "//isms.kopexa.dev/tenants/acme/workspaces/main/evidences/ev-1",
+		"Resolve":        "//isms.kopexa.dev/tenants/acme/workspaces/main/evidences/ev-1",
+	}
+	for name, d := range derived {
+		if d.String() != want[name] {
+			t.Errorf("%s = %q, want %q", name, d.String(), want[name])
+		}
+	}
+
+	if !k.EqualsString("//isms.kopexa.dev/tenants/acme/workspaces/main@v1") {
+		t.Error("expected EqualsString to use the KRN's domain")
+	}
+
+	t.Run("different domains are different resources", func(t *testing.T) {
+		def := MustParse("//isms.kopexa.com/tenants/acme/workspaces/main@v1")
+		if k.Equals(def) || CanMerge(k, def) || k.Compare(def) == 0 {
+			t.Error("expected KRNs in different domains to differ")
+		}
+		if def.IsAncestorOf(child) || CommonAncestor(def, k) != nil {
+			t.Error("expected no hierarchy across domains")
+		}
+		if MustParsePattern("//isms.kopexa.com/tenants/acme/**").Match(k) {
+			t.Error("expected default-domain pattern not to match")
+		}
+	})
+}
+
+func TestWithStrictCollections(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"valid", "//kopexa.com/frameworks/iso27001/control-implementations/ci-1", false},
+		{"uppercase", "//kopexa.com/Frameworks/iso27001", true},
+		{"space", "//kopexa.com/my collection/x/controls/a", true},
+		{"underscore in nested collection", "//kopexa.com/frameworks/iso27001/control_sets/a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, WithStrictCollections())
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCollection) || !errors.Is(err, ErrInvalidKRN) {
+				t.Errorf("expected ErrInvalidCollection, got %v", err)
+			}
+
+			// Lenient by default
+			if _, err := Parse(tt.input); err != nil {
+				t.Errorf("Parse() should stay lenient, got %v", err)
+			}
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		_, err := ParseWithOptions("//kopexa.com/frameworks/iso27001/Controls/a", WithStrictCollections())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "Controls" || pe.Offset != 33 || pe.Segment != 1 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("combined with domain", func(t *testing.T) {
+		if _, err := ParseWithOptions("//kopexa.dev/Frameworks/x", WithDomain("kopexa.dev"), WithStrictCollections()); !errors.Is(err, ErrInvalidCollection) {
+			t.Errorf("expected ErrInvalidCollection, got %v", err)
+		}
+	})
+}
+
+func TestWithDecoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"email", "//kopexa.com/users/jane%40example.com", "//kopexa.com/users/x--jane_40example.com", nil},
+		{"slash", "//kopexa.com/tenants/acme/repos/org%2Frepo@v1", "//kopexa.com/tenants/acme/repos/x--org_2Frepo@v1", nil},
+		{"decodes to plain ID", "//kopexa.com/tenants/acme%2Dcorp", "//kopexa.com/tenants/acme-corp", nil},
+		{"plain IDs untouched", "//kopexa.com/frameworks/iso27001/controls/a_5_1", "//kopexa.com/frameworks/iso27001/controls/a_5_1", nil},
+		{"literal escape-like ID untouched", "//kopexa.com/users/a_40", "//kopexa.com/users/a_40", nil},
+		{"leading reserved byte", "//kopexa.com/users/%40jane", "//kopexa.com/users/x--_40jane", nil},
+		{"malformed escape", "//kopexa.com/users/jane%4", "", ErrInvalidResourceID},
+		{"too long when encoded", "//kopexa.com/users/" + strings.Repeat("%40", 100), "", ErrInvalidResourceID},
+		{"empty collection", "//kopexa.com//jane%40example.com", "", ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseWithOptions(tt.input, WithDecoding())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.String() != tt.want {
+				t.Errorf("got %s, want %s", k, tt.want)
+			}
+			if again, err := Parse(k.String()); err != nil || !again.Equals(k) {
+				t.Errorf("canonical form does not round-trip: %v", err)
+			}
+		})
+	}
+
+	t.Run("decoded ID", func(t *testing.T) {
+		k, _ := ParseWithOptions("//kopexa.com/users/jane%40example.com/keys/k1", WithDecoding())
+		id, err := DecodeResourceID(k.MustResourceID("users"))
+		if err != nil || id != "jane@example.com" {
+			t.Errorf("DecodeResourceID() = (%q, %v)", id, err)
+		}
+	})
+
+	t.Run("no collisions", func(t *testing.T) {
+		encoded, _ := ParseWithOptions("//kopexa.com/users/a%40", WithDecoding())
+		literal, _ := ParseWithOptions("//kopexa.com/users/a_40", WithDecoding())
+		if encoded.Equals(literal) {
+			t.Errorf("%s and %s collide", encoded, literal)
+		}
+	})
+
+	t.Run("error position", func(t *testing.T) {
+		_, err := ParseWithOptions("//kopexa.com/users/a/keys/x%zz/more/y", WithDecoding())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "x%zz" || pe.Offset != 26 || pe.Segment != 1 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("offsets after decoded IDs", func(t *testing.T) {
+		_, err := ParseWithOptions("//kopexa.com/users/a%40b/keys/-bad", WithDecoding())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "-bad" || pe.Offset != 30 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Parse rejects percent escapes", func(t *testing.T) {
+		if _, err := Parse("//kopexa.com/users/jane%40example.com"); !errors.Is(err, ErrInvalidResourceID) {
+			t.Errorf("expected ErrInvalidResourceID, got %v", err)
+		}
+	})
+}
+
+func TestVersionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []Option
+		wantErr error
+	}{
+		{"required, present", "//kopexa.com/frameworks/iso27001@v1", []Option{WithRequireVersion()}, nil},
+		{"required, missing", "//kopexa.com/frameworks/iso27001", []Option{WithRequireVersion()}, ErrVersionRequired},
+		{"forbidden, missing", "//kopexa.com/frameworks/iso27001", []Option{WithForbidVersion()}, nil},
+		{"forbidden, present", "//kopexa.com/frameworks/iso27001@v1", []Option{WithForbidVersion()}, ErrVersionForbidden},
+		{"last option wins", "//kopexa.com/frameworks/iso27001", []Option{WithRequireVersion(), WithForbidVersion()}, nil},
+		{"invalid version reported first", "//kopexa.com/frameworks/iso27001@-v", []Option{WithForbidVersion()}, ErrInvalidVersion},
+		{"combined with domain", "//kopexa.dev/frameworks/iso27001", []Option{WithDomain("kopexa.dev"), WithRequireVersion()}, ErrVersionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, ErrInvalidVersion) {
+				t.Errorf("expected error to wrap ErrInvalidVersion, got %v", err)
+			}
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		const input = "//kopexa.com/frameworks/iso27001@v1.2"
+		_, err := ParseWithOptions(input, WithForbidVersion())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "v1.2" || input[pe.Offset:] != "v1.2" || pe.Segment != -1 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+
+		_, err = ParseWithOptions("//kopexa.com/frameworks/iso27001", WithRequireVersion())
+		if !errors.As(err, &pe) || pe.Offset != len("//kopexa.com/frameworks/iso27001") {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		n       int
+		wantErr bool
+	}{
+		{"at limit", "//kopexa.com/tenants/acme/workspaces/main", 2, false},
+		{"below limit with version", "//kopexa.com/tenants/acme@v1", 2, false},
+		{"above limit", "//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", 2, true},
+		{"dangling collection above limit", "//kopexa.com/tenants/acme/workspaces/main/evidences", 2, true},
+		{"zero is unbounded", "//kopexa.com/a/1/b/2/c/3/d/4/e/5", 0, false},
+		{"negative is unbounded", "//kopexa.com/a/1/b/2/c/3/d/4/e/5", -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, WithMaxDepth(tt.n))
+			if tt.wantErr != errors.Is(err, ErrInvalidKRN) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		const input = "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v1"
+		_, err := ParseWithOptions(input, WithMaxDepth(2))
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "evidences/ev-1" || input[pe.Offset:] != "evidences/ev-1@v1" || pe.Segment != 2 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+		if !strings.Contains(err.Error(), "exceeds maximum depth 2") {
+			t.Errorf("unexpected message: %v", err)
+		}
+	})
+
+	t.Run("deep input does not allocate segments", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("the race detector adds allocations")
+		}
+		input := "//kopexa.com" + strings.Repeat("/a/b", 10000)
+		allocs := testing.AllocsPerRun(10, func() {
+			_, _ = ParseWithOptions(input, WithMaxDepth(4))
+		})
+		if allocs > 5 {
+			t.Errorf("expected rejection without allocating segments, got %v allocs", allocs)
+		}
+	})
+}
+
+func TestWithMaxResourceIDLength(t *testing.T) {
+	long := strings.Repeat("a", 65)
+
+	tests := []struct {
+		name    string
+		input   string
+		n       int
+		wantErr bool
+	}{
+		{"at limit", "//kopexa.com/tenants/" + long[:64], 64, false},
+		{"above limit", "//kopexa.com/tenants/" + long, 64, true},
+		{"above limit in parent", "//kopexa.com/tenants/" + long + "/workspaces/main", 64, true},
+		{"zero keeps default", "//kopexa.com/tenants/" + long, 0, false},
+		{"cannot raise default", "//kopexa.com/tenants/" + strings.Repeat("a", MaxResourceIDLength+1), 500, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, WithMaxResourceIDLength(tt.n))
+			if tt.wantErr != errors.Is(err, ErrInvalidResourceID) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		input := "//kopexa.com/tenants/acme/workspaces/" + long
+		_, err := ParseWithOptions(input, WithMaxResourceIDLength(64))
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != long || input[pe.Offset:] != long || pe.Segment != 1 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+		if !strings.Contains(err.Error(), "exceeds maximum length 64") {
+			t.Errorf("unexpected message: %v", err)
+		}
+	})
+}
+
+func TestWithQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"query", "//kopexa.com/frameworks/iso27001?region=eu", "//kopexa.com/frameworks/iso27001?region=eu", nil},
+		{"after version", "//catalog.kopexa.com/frameworks/iso27001@v1?region=eu&rev=abc", "//catalog.kopexa.com/frameworks/iso27001@v1?region=eu&rev=abc", nil},
+		{"canonical order and escaping", "//kopexa.com/frameworks/iso27001?rev=a%20b&region=eu", "//kopexa.com/frameworks/iso27001?region=eu&rev=a+b", nil},
+		{"at sign in query", "//kopexa.com/users/jane?email=jane@example.com", "//kopexa.com/users/jane?email=jane%40example.com", nil},
+		{"empty query is dropped", "//kopexa.com/frameworks/iso27001?", "//kopexa.com/frameworks/iso27001", nil},
+		{"no query", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", nil},
+		{"malformed query", "//kopexa.com/frameworks/iso27001?region=%zz", "", ErrInvalidKRN},
+		{"invalid version before query", "//kopexa.com/frameworks/iso27001@-v1?region=eu", "", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseWithOptions(tt.input, WithQuery())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if k.String() != tt.want {
+				t.Errorf("String() = %q, want %q", k.String(), tt.want)
+			}
+			if k.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", k.Len(), len(tt.want))
+			}
+		})
+	}
+
+	t.Run("rejected without option", func(t *testing.T) {
+		if _, err := Parse("//kopexa.com/frameworks/iso27001?region=eu"); err == nil {
+			t.Error("expected Parse to reject a query")
+		}
+		if _, err := ParseWithOptions("//kopexa.com/frameworks/iso27001@v1?region=eu"); err == nil {
+			t.Error("expected ParseWithOptions to reject a query without WithQuery")
+		}
+	})
+
+	t.Run("query values", func(t *testing.T) {
+		k, err := ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu&tag=a&tag=b", WithQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := k.Query()
+		if q.Get("region") != "eu" || len(q["tag"]) != 2 {
+			t.Errorf("Query() = %v", q)
+		}
+		q.Set("region", "us")
+		if k.Query().Get("region") != "eu" {
+			t.Error("Query() must return a copy")
+		}
+		if len(MustParse("//kopexa.com/frameworks/iso27001").Query()) != 0 {
+			t.Error("expected an empty query")
+		}
+	})
+
+	t.Run("derived KRNs", func(t *testing.T) {
+		k, err := ParseWithOptions("//kopexa.com/frameworks/iso27001/controls/a-5-1?region=eu", WithQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k.Clone().Equals(k) {
+			t.Error("expected Clone to keep the query")
+		}
+		if k.Equals(MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")) {
+			t.Error("expected the query to take part in Equals")
+		}
+		if got := k.Parent().String(); got != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("Parent() = %s", got)
+		}
+	})
+
+	t.Run("version policy offset", func(t *testing.T) {
+		const input = "//kopexa.com/frameworks/iso27001@v1?region=eu"
+		_, err := ParseWithOptions(input, WithQuery(), WithForbidVersion())
+		var pe *ParseError
+		if !errors.As(err, &pe) || input[pe.Offset:pe.Offset+len(pe.Value)] != "v1" {
+			t.Errorf("unexpected error: %#v", err)
+		}
+
+		_, err = ParseWithOptions("//kopexa.com/frameworks/iso27001?region=eu", WithQuery(), WithRequireVersion())
+		if !errors.As(err, &pe) || pe.Offset != len("//kopexa.com/frameworks/iso27001") {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("query error position", func(t *testing.T) {
+		const input = "//kopexa.com/frameworks/iso27001?region=%zz"
+		_, err := ParseWithOptions(input, WithQuery())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "region=%zz" || input[pe.Offset:] != pe.Value {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+}
+
+func TestWithUniqueCollections(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"//kopexa.com/frameworks/iso27001/controls/a-5-1@v1", false},
+		{"//kopexa.com/controls/a/controls/b", true},
+		{"//isms.kopexa.com/tenants/acme/workspaces/main/tenants/globex", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, WithUniqueCollections())
+			if tt.wantErr != errors.Is(err, ErrInvalidKRN) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if _, err := Parse(tt.input); err != nil {
+				t.Errorf("expected Parse to accept %s, got %v", tt.input, err)
+			}
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		const input = "//kopexa.com/controls/a/controls/b"
+		_, err := ParseWithOptions(input, WithUniqueCollections())
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Value != "controls" || input[pe.Offset:] != "controls/b" || pe.Segment != 1 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"sync"
+)
+
+// collectionServices is the registry of collection owners, keyed by collection.
+var (
+	collectionServicesMu sync.RWMutex
+	collectionServices   = map[string]string{}
+)
+
+// RegisterCollectionService records that KRNs containing collection belong to
+// service, e.g. that frameworks only live under the catalog service:
+//
+//	krn.RegisterCollectionService("frameworks", "catalog")
+//
+// Registering a collection again replaces its service. Returns
+// ErrInvalidCollection if collection fails IsValidCollection and
+// ErrInvalidService if service is not a valid service name. The registry is
+// global; register owners during program initialization.
+func RegisterCollectionService(collection, service string) error {
+	if !IsValidCollection(collection) {
+		return fmt.Errorf("%w: %s", ErrInvalidCollection, collection)
+	}
+	if !IsValidService(service) {
+		return serviceError(service)
+	}
+
+	collectionServicesMu.Lock()
+	defer collectionServicesMu.Unlock()
+	collectionServices[collection] = service
+	return nil
+}
+
+// LookupCollectionService returns the service registered for collection, if any.
+func LookupCollectionService(collection string) (string, bool) {
+	collectionServicesMu.RLock()
+	defer collectionServicesMu.RUnlock()
+	service, ok := collectionServices[collection]
+	return service, ok
+}
+
+// ValidateServiceOwnership checks every segment's collection against the
+// registry of RegisterCollectionService and returns an error wrapping
+// ErrServiceMismatch for the first collection, in path order, whose registered
+// service differs from k's service (including KRNs without a service).
+// Collections without a registered service are not checked. Returns nil if
+// every registered collection is under its service.
+func (k *KRN) ValidateServiceOwnership() error {
+	collectionServicesMu.RLock()
+	defer collectionServicesMu.RUnlock()
+
+	for i, seg := range k.segments {
+		service, ok := collectionServices[seg.Collection]
+		if ok && service != k.service {
+			return fmt.Errorf("%w: %s: collection %q at segment %d belongs to service %q", ErrServiceMismatch, k, seg.Collection, i, service)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// restoreCollectionServices restores the collection owner registry after the test.
+func restoreCollectionServices(t *testing.T) {
+	t.Helper()
+	collectionServicesMu.RLock()
+	saved := maps.Clone(collectionServices)
+	collectionServicesMu.RUnlock()
+	t.Cleanup(func() {
+		collectionServicesMu.Lock()
+		collectionServices = saved
+		collectionServicesMu.Unlock()
+	})
+}
+
+func TestRegisterCollectionService(t *testing.T) {
+	restoreCollectionServices(t)
+
+	if _, ok := LookupCollectionService("frameworks"); ok {
+		t.Fatal("expected no owner before registration")
+	}
+	if err := RegisterCollectionService("frameworks", "catalog"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service, ok := LookupCollectionService("frameworks"); !ok || service != "catalog" {
+		t.Errorf("LookupCollectionService() = (%q, %v)", service, ok)
+	}
+
+	if err := RegisterCollectionService("frameworks", "isms"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service, _ := LookupCollectionService("frameworks"); service != "isms" {
+		t.Errorf("expected re-registration to replace the owner, got %q", service)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if err := RegisterCollectionService("Frameworks", "catalog"); !errors.Is(err, ErrInvalidCollection) {
+			t.Errorf("expected ErrInvalidCollection, got %v", err)
+		}
+		if err := RegisterCollectionService("frameworks", ""); !errors.Is(err, ErrInvalidService) {
+			t.Errorf("expected ErrInvalidService, got %v", err)
+		}
+		if err := RegisterCollectionService("frameworks", "Catalog"); !errors.Is(err, ErrInvalidService) {
+			t.Errorf("expected ErrInvalidService, got %v", err)
+		}
+	})
+}
+
+func TestKRN_ValidateServiceOwnership(t *testing.T) {
+	restoreCollectionServices(t)
+	for collection, service := range map[string]string{"frameworks": "catalog", "tenants": "isms"} {
+		if err := RegisterCollectionService(collection, service); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"owned leaf", "//catalog.kopexa.com/frameworks/iso27001/controls/a-5-1", ""},
+		{"owned root", "//isms.kopexa.com/tenants/acme/workspaces/main", ""},
+		{"unregistered collections", "//kopexa.com/policies/p-1/controls/c-1", ""},
+		{"wrong service", "//isms.kopexa.com/frameworks/iso27001", `collection "frameworks" at segment 0 belongs to service "catalog"`},
+		{"missing service", "//kopexa.com/frameworks/iso27001", `collection "frameworks" at segment 0 belongs to service "catalog"`},
+		{"nested under wrong service", "//isms.kopexa.com/tenants/acme/frameworks/iso27001", `collection "frameworks" at segment 1`},
+		{"first mismatch reported", "//kopexa.com/tenants/acme/frameworks/iso27001", `collection "tenants" at segment 0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MustParse(tt.input).ValidateServiceOwnership()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrServiceMismatch) || errors.Is(err, ErrInvalidService) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected ErrServiceMismatch containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern wildcards.
+const (
+	wildcardOne  = "*"
+	wildcardRest = "**"
+)
+
+// Pattern matches KRNs against an IAM-style policy pattern such as
+// //kopexa.com/tenants/*/workspaces/* or //isms.kopexa.com/tenants/acme/**.
+//
+// Patterns use the KRN syntax. Collections and the service are literal. A
+// resource ID may be "*", which matches any single resource ID in that
+// collection. The last element may be "**", which matches any remaining
+// segments:
+//
+//	//kopexa.com/tenants/acme/**   tenants/acme and all of its descendants
+//	//kopexa.com/frameworks/**     every framework and all of its descendants
+//	//kopexa.com/**                every KRN without a service
+//
+// A pattern without a version, or with the version VersionAny ("@*"), matches
+// KRNs at any version, including unversioned ones. A literal version such as
+// "@v1" only matches KRNs with exactly that version (see MatchesVersion).
+type Pattern struct {
+	service  string
+	segments []Segment // ResourceID is a literal or wildcardOne
+	rest     bool      // trailing "**"
+	version  string    // VersionAny or a literal version
+}
+
+// ParsePattern parses a pattern string. See Pattern for the syntax.
+// Returns ErrEmptyKRN for empty input, ErrInvalidKRN for malformed patterns
+// (including wildcards in collections), ErrInvalidDomain for an invalid host,
+// ErrInvalidResourceID for invalid literal resource IDs and ErrInvalidVersion
+// for an invalid version.
+func ParsePattern(s string) (*Pattern, error) {
+	if s == "" {
+		return nil, ErrEmptyKRN
+	}
+	if !strings.HasPrefix(s, "//") {
+		return nil, fmt.Errorf("%w: pattern must start with //", ErrInvalidKRN)
+	}
+
+	body, version, ok := SplitVersion(s[2:])
+	if !ok {
+		version = VersionAny
+	} else if version != VersionAny && !IsValidVersion(version) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+	}
+
+	parts := strings.Split(body, "/")
+	service, err := parseHost(parts[0], Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, rest, err := parsePatternPath(parts[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pattern{
+		service:  service,
+		segments: segments,
+		rest:     rest,
+		version:  version,
+	}, nil
+}
+
+// parsePatternPath parses the collection/id elements of a pattern.
+func parsePatternPath(path []string) ([]Segment, bool, error) {
+	rest := len(path) > 0 && path[len(path)-1] == wildcardRest
+	if rest {
+		path = path[:len(path)-1]
+		if len(path)%2 != 0 {
+			// "collection/**" matches every resource in the collection.
+			path = append(path, wildcardOne)
+		}
+	}
+
+	if len(path)%2 != 0 || (len(path) == 0 && !rest) {
+		return nil, false, fmt.Errorf("%w: pattern path must be pairs of collection/id", ErrInvalidKRN)
+	}
+
+	segments := make([]Segment, 0, len(path)/2)
+	for i := 0; i < len(path); i += 2 {
+		collection, resourceID := path[i], path[i+1]
+		if collection == "" || strings.Contains(collection, wildcardOne) {
+			return nil, false, fmt.Errorf("%w: invalid pattern collection %q", ErrInvalidKRN, collection)
+		}
+		if resourceID != wildcardOne && !IsValidResourceID(resourceID) {
+			return nil, false, fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID)
+		}
+		segments = append(segments, Segment{Collection: collection, ResourceID: resourceID})
+	}
+
+	return segments, rest, nil
+}
+
+// MustParsePattern parses a pattern string and panics on error.
+func MustParsePattern(s string) *Pattern {
+	p, err := ParsePattern(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Glob returns the candidates that match pattern, in their original order and
+// unchanged, e.g. to filter KRN strings in scripts without parsing each one by
+// hand. The pattern is parsed with ParsePattern: "*" matches any single resource
+// ID and a trailing "**" any remaining segments. Candidates that are not valid
+// KRNs never match. Returns nil if no candidate matches, or the ParsePattern
+// error if the pattern is invalid.
+func Glob(pattern string, candidates []string) ([]string, error) {
+	p, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, s := range candidates {
+		if k, err := Parse(s); err == nil && p.Match(k) {
+			matches = append(matches, s)
+		}
+	}
+	return matches, nil
+}
+
+// Match reports whether k matches the pattern. Patterns always use the default
+// Domain, so KRNs parsed with WithDomain never match. Returns false if k is nil.
+func (p *Pattern) Match(k *KRN) bool {
+	if k == nil || k.domain != "" || k.service != p.service || !MatchesVersion(p.version, k.version) {
+		return false
+	}
+	if len(k.segments) < len(p.segments) || (!p.rest && len(k.segments) != len(p.segments)) {
+		return false
+	}
+
+	for i, seg := range p.segments {
+		if seg.Collection != k.segments[i].Collection {
+			return false
+		}
+		if seg.ResourceID != wildcardOne && seg.ResourceID != k.segments[i].ResourceID {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical pattern string. A trailing "collection/*/**"
+// is written in its equivalent short form "collection/**", and VersionAny is
+// omitted.
+func (p *Pattern) String() string {
+	s := p.path()
+	if p.version != VersionAny {
+		s += "@" + p.version
+	}
+	return s
+}
+
+// path returns the pattern string without the version.
+func (p *Pattern) path() string {
+	var sb strings.Builder
+	sb.WriteString("//")
+	if p.service != "" {
+		sb.WriteString(p.service)
+		sb.WriteString(".")
+	}
+	sb.WriteString(Domain)
+
+	for i, seg := range p.segments {
+		sb.WriteString("/")
+		sb.WriteString(seg.Collection)
+		if p.rest && i == len(p.segments)-1 && seg.ResourceID == wildcardOne {
+			sb.WriteString("/")
+			sb.WriteString(wildcardRest)
+			return sb.String()
+		}
+		sb.WriteString("/")
+		sb.WriteString(seg.ResourceID)
+	}
+	if p.rest {
+		sb.WriteString("/")
+		sb.WriteString(wildcardRest)
+	}
+	return sb.String()
+}
+
+// PatternSet matches KRNs against many patterns at once. Patterns are grouped
+// by service and first collection, so a KRN is only checked against patterns
+// that can possibly match it instead of scanning the whole set.
+type PatternSet struct {
+	patterns []*Pattern
+	groups   map[string][]int // group key -> indices into patterns, ascending
+}
+
+// NewPatternSet returns a set containing patterns. Nil entries are ignored.
+func NewPatternSet(patterns ...*Pattern) *PatternSet {
+	s := &PatternSet{groups: make(map[string][]int)}
+	for _, p := range patterns {
+		if p == nil {
+			continue
+		}
+		key := p.groupKey()
+		s.groups[key] = append(s.groups[key], len(s.patterns))
+		s.patterns = append(s.patterns, p)
+	}
+	return s
+}
+
+// groupKey returns the bucket of a pattern: its service and first collection,
+// or just the service for patterns that match any collection ("//kopexa.com/**").
+func (p *Pattern) groupKey() string {
+	if len(p.segments) == 0 {
+		return p.service
+	}
+	return p.service + "/" + p.segments[0].Collection
+}
+
+// Len returns the number of patterns in the set.
+func (s *PatternSet) Len() int {
+	return len(s.patterns)
+}
+
+// Matches reports whether any pattern in the set matches k.
+func (s *PatternSet) Matches(k *KRN) bool {
+	found := false
+	s.candidates(k, func(p *Pattern) bool {
+		found = p.Match(k)
+		return !found
+	})
+	return found
+}
+
+// AllMatching returns the patterns that match k, in the order they were added.
+// Returns nil if no pattern matches.
+func (s *PatternSet) AllMatching(k *KRN) []Pattern {
+	var matching []Pattern
+	s.candidates(k, func(p *Pattern) bool {
+		if p.Match(k) {
+			matching = append(matching, *p)
+		}
+		return true
+	})
+	return matching
+}
+
+// candidates calls fn for every pattern that may match k, in insertion order,
+// until fn returns false.
+func (s *PatternSet) candidates(k *KRN, fn func(*Pattern) bool) {
+	if k == nil || len(k.segments) == 0 {
+		return
+	}
+	wild := s.groups[k.service]
+	first := s.groups[k.service+"/"+k.segments[0].Collection]
+
+	// Merge the two ascending index lists to keep insertion order.
+	for len(wild) > 0 || len(first) > 0 {
+		var i int
+		if len(first) == 0 || (len(wild) > 0 && wild[0] < first[0]) {
+			i, wild = wild[0], wild[1:]
+		} else {
+			i, first = first[0], first[1:]
+		}
+		if !fn(s.patterns[i]) {
+			return
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		// Literal patterns
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", true},
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001@v2", true},
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/nist", false},
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001/controls/a-5-1", false},
+
+		// Single wildcards
+		{"//kopexa.com/frameworks/*", "//kopexa.com/frameworks/iso27001", true},
+		{"//kopexa.com/frameworks/*", "//kopexa.com/policies/iso27001", false},
+		{"//kopexa.com/frameworks/*", "//kopexa.com/frameworks/iso27001/controls/a-5-1", false},
+		{"//kopexa.com/tenants/*/workspaces/*", "//kopexa.com/tenants/acme/workspaces/main", true},
+		{"//kopexa.com/tenants/*/workspaces/*", "//kopexa.com/tenants/acme", false},
+		{"//kopexa.com/tenants/*/workspaces/*", "//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", false},
+
+		// Trailing **
+		{"//isms.kopexa.com/tenants/acme/**", "//isms.kopexa.com/tenants/acme", true},
+		{"//isms.kopexa.com/tenants/acme/**", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", true},
+		{"//isms.kopexa.com/tenants/acme/**", "//isms.kopexa.com/tenants/globex/workspaces/main", false},
+		{"//kopexa.com/frameworks/**", "//kopexa.com/frameworks/iso27001", true},
+		{"//kopexa.com/frameworks/**", "//kopexa.com/frameworks/iso27001/controls/a-5-1", true},
+		{"//kopexa.com/frameworks/**", "//kopexa.com/policies/p-1", false},
+		{"//kopexa.com/tenants/*/**", "//kopexa.com/tenants/acme/workspaces/main", true},
+		{"//kopexa.com/**", "//kopexa.com/frameworks/iso27001", true},
+
+		// Versions
+		{"//kopexa.com/frameworks/iso27001@*", "//kopexa.com/frameworks/iso27001@v1", true},
+		{"//kopexa.com/frameworks/iso27001@*", "//kopexa.com/frameworks/iso27001@v2", true},
+		{"//kopexa.com/frameworks/iso27001@*", "//kopexa.com/frameworks/iso27001", true},
+		{"//kopexa.com/frameworks/*@v1", "//kopexa.com/frameworks/iso27001@v1", true},
+		{"//kopexa.com/frameworks/*@v1", "//kopexa.com/frameworks/iso27001@v2", false},
+		{"//kopexa.com/frameworks/*@v1", "//kopexa.com/frameworks/iso27001", false},
+		{"//kopexa.com/frameworks/**@latest", "//kopexa.com/frameworks/iso27001/controls/a-5-1@latest", true},
+
+		// Services must match exactly
+		{"//kopexa.com/**", "//catalog.kopexa.com/frameworks/iso27001", false},
+		{"//catalog.kopexa.com/frameworks/*", "//kopexa.com/frameworks/iso27001", false},
+		{"//catalog.kopexa.com/frameworks/*", "//catalog.kopexa.com/frameworks/iso27001", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.input, func(t *testing.T) {
+			p := MustParsePattern(tt.pattern)
+			if got := p.Match(MustParse(tt.input)); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParsePattern("//kopexa.com/**").Match(nil) {
+			t.Error("expected Match(nil) to be false")
+		}
+	})
+}
+
+func TestParsePattern(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		inputs := []string{
+			"//kopexa.com/frameworks/iso27001",
+			"//kopexa.com/tenants/*/workspaces/*",
+			"//isms.kopexa.com/tenants/acme/**",
+			"//kopexa.com/**",
+			"//kopexa.com/frameworks/**",
+			"//kopexa.com/frameworks/iso27001@v1",
+			"//kopexa.com/frameworks/**@draft",
+		}
+		for _, input := range inputs {
+			if got := MustParsePattern(input).String(); got != input {
+				t.Errorf("String() = %q, want %q", got, input)
+			}
+		}
+		if got := MustParsePattern("//kopexa.com/frameworks/*/**").String(); got != "//kopexa.com/frameworks/**" {
+			t.Errorf("String() = %q", got)
+		}
+		if got := MustParsePattern("//kopexa.com/frameworks/iso27001@*").String(); got != "//kopexa.com/frameworks/iso27001" {
+			t.Errorf("String() = %q", got)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			input   string
+			wantErr error
+		}{
+			{"empty", "", ErrEmptyKRN},
+			{"no prefix", "kopexa.com/frameworks/*", ErrInvalidKRN},
+			{"invalid version", "//kopexa.com/frameworks/*@-v1", ErrInvalidVersion},
+			{"empty version", "//kopexa.com/frameworks/*@", ErrInvalidVersion},
+			{"partial version wildcard", "//kopexa.com/frameworks/*@v1.*", ErrInvalidVersion},
+			{"invalid domain", "//example.com/frameworks/*", ErrInvalidDomain},
+			{"invalid service", "//Catalog.kopexa.com/frameworks/*", ErrInvalidService},
+			{"domain only", "//kopexa.com", ErrInvalidKRN},
+			{"collection only", "//kopexa.com/frameworks", ErrInvalidKRN},
+			{"wildcard collection", "//kopexa.com/*/iso27001", ErrInvalidKRN},
+			{"empty collection", "//kopexa.com//iso27001", ErrInvalidKRN},
+			{"double wildcard in middle", "//kopexa.com/tenants/**/workspaces/main", ErrInvalidResourceID},
+			{"partial wildcard", "//kopexa.com/frameworks/iso*", ErrInvalidResourceID},
+			{"invalid resource ID", "//kopexa.com/frameworks/-bad", ErrInvalidResourceID},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := ParsePattern(tt.input); !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("must parse panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		MustParsePattern("")
+	})
+}
+
+func TestGlob(t *testing.T) {
+	candidates := []string{
+		"//kopexa.com/tenants/acme/workspaces/main",
+		"//kopexa.com/tenants/acme",
+		"not a krn",
+		"//kopexa.com/tenants/globex/workspaces/dev@v2",
+		"//isms.kopexa.com/tenants/acme/workspaces/main",
+		"//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1",
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"single wildcard", "//kopexa.com/tenants/*/workspaces/*", []string{candidates[0], candidates[3]}},
+		{"descendants", "//kopexa.com/tenants/acme/**", []string{candidates[0], candidates[1], candidates[5]}},
+		{"service", "//isms.kopexa.com/**", []string{candidates[4]}},
+		{"no match", "//kopexa.com/frameworks/*", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Glob(tt.pattern, candidates)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Glob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := Glob("//kopexa.com/*/iso27001", candidates); !errors.Is(err, ErrInvalidKRN) {
+			t.Errorf("expected ErrInvalidKRN, got %v", err)
+		}
+	})
+}
+
+func TestPatternSet(t *testing.T) {
+	s := NewPatternSet(
+		MustParsePattern("//kopexa.com/tenants/*/workspaces/*"),
+		MustParsePattern("//kopexa.com/**"),
+		nil,
+		MustParsePattern("//kopexa.com/frameworks/iso27001/**"),
+		MustParsePattern("//kopexa.com/tenants/acme/**"),
+		MustParsePattern("//catalog.kopexa.com/frameworks/*"),
+	)
+	if s.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5", s.Len())
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"//kopexa.com/tenants/acme/workspaces/main", []string{
+			"//kopexa.com/tenants/*/workspaces/*",
+			"//kopexa.com/**",
+			"//kopexa.com/tenants/acme/**",
+		}},
+		{"//kopexa.com/frameworks/iso27001/controls/a-5-1", []string{
+			"//kopexa.com/**",
+			"//kopexa.com/frameworks/iso27001/**",
+		}},
+		{"//catalog.kopexa.com/frameworks/nist", []string{
+			"//catalog.kopexa.com/frameworks/*",
+		}},
+		{"//catalog.kopexa.com/policies/p-1", nil},
+		{"//isms.kopexa.com/tenants/acme", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			k := MustParse(tt.input)
+			if got := s.Matches(k); got != (len(tt.want) > 0) {
+				t.Errorf("Matches() = %v, want %v", got, len(tt.want) > 0)
+			}
+
+			got := s.AllMatching(k)
+			if len(got) != len(tt.want) {
+				t.Fatalf("AllMatching() returned %d patterns, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("AllMatching()[%d] = %s, want %s", i, got[i].String(), tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if s.Matches(nil) || s.AllMatching(nil) != nil {
+			t.Error("expected no match for nil")
+		}
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		if NewPatternSet().Matches(MustParse("//kopexa.com/frameworks/iso27001")) {
+			t.Error("expected no match")
+		}
+	})
+}
+
+// benchmarkPatterns returns n patterns spread over many first collections.
+func benchmarkPatterns(n int) []*Pattern {
+	patterns := make([]*Pattern, 0, n)
+	for i := 0; i < n; i++ {
+		patterns = append(patterns, MustParsePattern(fmt.Sprintf("//kopexa.com/collection-%d/*/items/item-%d", i%100, i)))
+	}
+	return patterns
+}
+
+func BenchmarkPatternSet_Matches(b *testing.B) {
+	patterns := benchmarkPatterns(1000)
+	k := MustParse("//kopexa.com/collection-42/x/items/item-none")
+
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range patterns {
+				if p.Match(k) {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("set", func(b *testing.B) {
+		s := NewPatternSet(patterns...)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Matches(k)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build race
+
+package krn
+
+// raceEnabled reports whether the tests run with the race detector, which
+// changes allocation counts.
+const raceEnabled = true
@@ -0,0 +1,67 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+// Set is a set of KRNs keyed by their canonical string, for membership checks
+// and deduplication. KRNs that differ only in version are distinct members.
+// The zero value is an empty set ready to use. A Set is not safe for
+// concurrent use.
+type Set struct {
+	m map[string]*KRN
+}
+
+// NewSet returns a set containing ks. Nil entries are ignored.
+func NewSet(ks ...*KRN) *Set {
+	s := &Set{m: make(map[string]*KRN, len(ks))}
+	for _, k := range ks {
+		s.Add(k)
+	}
+	return s
+}
+
+// Add adds k to the set. Adding a KRN that is already a member, or nil, is a no-op.
+func (s *Set) Add(k *KRN) {
+	if k == nil {
+		return
+	}
+	if s.m == nil {
+		s.m = make(map[string]*KRN)
+	}
+	key := k.String()
+	if _, ok := s.m[key]; !ok {
+		s.m[key] = k
+	}
+}
+
+// Contains reports whether k is a member of the set.
+func (s *Set) Contains(k *KRN) bool {
+	if k == nil {
+		return false
+	}
+	_, ok := s.m[k.String()]
+	return ok
+}
+
+// Remove removes k from the set. Removing a non-member is a no-op.
+func (s *Set) Remove(k *KRN) {
+	if k == nil {
+		return
+	}
+	delete(s.m, k.String())
+}
+
+// Len returns the number of members.
+func (s *Set) Len() int {
+	return len(s.m)
+}
+
+// Slice returns the members sorted by Compare.
+func (s *Set) Slice() []*KRN {
+	ks := make([]*KRN, 0, len(s.m))
+	for _, k := range s.m {
+		ks = append(ks, k)
+	}
+	SortKRNs(ks)
+	return ks
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	iso := MustParse("//kopexa.com/frameworks/iso27001")
+	isoV1 := MustParse("//kopexa.com/frameworks/iso27001@v1")
+	nist := MustParse("//kopexa.com/frameworks/nist")
+	control := MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1")
+
+	s := NewSet(nist, iso, nil, MustParse("//kopexa.com/frameworks/iso27001"))
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+
+	s.Add(control)
+	s.Add(isoV1)
+	s.Add(MustParse("//kopexa.com/frameworks/iso27001/controls/a-5-1"))
+	s.Add(nil)
+	if s.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", s.Len())
+	}
+
+	for _, k := range []*KRN{iso, isoV1, nist, control, MustParse("//kopexa.com/frameworks/nist")} {
+		if !s.Contains(k) {
+			t.Errorf("expected set to contain %s", k)
+		}
+	}
+	if s.Contains(MustParse("//catalog.kopexa.com/frameworks/iso27001")) || s.Contains(nil) {
+		t.Error("unexpected member")
+	}
+
+	want := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001@v1",
+		"//kopexa.com/frameworks/iso27001/controls/a-5-1",
+		"//kopexa.com/frameworks/nist",
+	}
+	got := s.Slice()
+	if len(got) != len(want) {
+		t.Fatalf("Slice() returned %d KRNs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("Slice()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	s.Remove(MustParse("//kopexa.com/frameworks/iso27001@v1"))
+	s.Remove(MustParse("//kopexa.com/policies/p-1"))
+	s.Remove(nil)
+	if s.Len() != 3 || s.Contains(isoV1) || !s.Contains(iso) {
+		t.Errorf("unexpected set after Remove: %v", s.Slice())
+	}
+}
+
+func TestSet_ZeroValue(t *testing.T) {
+	var s Set
+	if s.Len() != 0 || len(s.Slice()) != 0 || s.Contains(MustParse("//kopexa.com/frameworks/iso27001")) {
+		t.Error("expected empty set")
+	}
+	s.Remove(MustParse("//kopexa.com/frameworks/iso27001"))
+
+	s.Add(MustParse("//kopexa.com/frameworks/iso27001"))
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", s.Len())
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Shape describes the fixed collection sequence of a resource type, e.g.
+// tenants/workspaces/evidences for evidences. A KRN conforms to a shape if its
+// collections are exactly the shape's collections, in order. Services,
+// resource IDs and versions are not checked.
+type Shape struct {
+	collections []string
+}
+
+// NewShape returns a shape with the given collections, from root to leaf:
+//
+//	evidence := krn.NewShape("tenants", "workspaces", "evidences")
+//
+// A shape without collections matches no KRN.
+func NewShape(collections ...string) *Shape {
+	return &Shape{collections: slices.Clone(collections)}
+}
+
+// Collections returns a copy of the shape's collections.
+func (s *Shape) Collections() []string {
+	return slices.Clone(s.collections)
+}
+
+// Matches reports whether k conforms to the shape. A nil KRN does not match.
+func (s *Shape) Matches(k *KRN) bool {
+	return s.Validate(k) == nil
+}
+
+// Validate returns nil if k conforms to the shape. Otherwise it returns
+// ErrEmptyKRN for a nil KRN, or an error wrapping ErrInvalidKRN that names the
+// first mismatching segment or the depth mismatch.
+func (s *Shape) Validate(k *KRN) error {
+	if k == nil {
+		return ErrEmptyKRN
+	}
+
+	for i := range min(len(s.collections), len(k.segments)) {
+		if got, want := k.segments[i].Collection, s.collections[i]; got != want {
+			return fmt.Errorf("%w: %s has collection %q at segment %d, shape %s requires %q", ErrInvalidKRN, k, got, i, s, want)
+		}
+	}
+	if len(k.segments) != len(s.collections) {
+		return fmt.Errorf("%w: %s has depth %d, shape %s requires %d", ErrInvalidKRN, k, len(k.segments), s, len(s.collections))
+	}
+	return nil
+}
+
+// String returns the shape as a path template, e.g. "tenants/*/workspaces/*".
+func (s *Shape) String() string {
+	parts := make([]string, len(s.collections))
+	for i, c := range s.collections {
+		parts[i] = c + "/" + wildcardOne
+	}
+	return strings.Join(parts, "/")
+}
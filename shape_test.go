@@ -0,0 +1,71 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShape(t *testing.T) {
+	evidence := NewShape("tenants", "workspaces", "evidences")
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"match", "//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", ""},
+		{"match with service and version", "//isms.kopexa.com/tenants/acme/workspaces/main/evidences/ev-1@v2", ""},
+		{"too shallow", "//kopexa.com/tenants/acme/workspaces/main", "has depth 2, shape tenants/*/workspaces/*/evidences/* requires 3"},
+		{"too deep", "//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1/files/f-1", "has depth 4"},
+		{"wrong order", "//kopexa.com/workspaces/main/tenants/acme/evidences/ev-1", `has collection "workspaces" at segment 0, shape tenants/*/workspaces/*/evidences/* requires "tenants"`},
+		{"wrong leaf", "//kopexa.com/tenants/acme/workspaces/main/controls/c-1", `has collection "controls" at segment 2`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MustParse(tt.input)
+			err := evidence.Validate(k)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if !evidence.Matches(k) {
+					t.Error("expected Matches to be true")
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidKRN) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected ErrInvalidKRN containing %q, got %v", tt.wantErr, err)
+			}
+			if evidence.Matches(k) {
+				t.Error("expected Matches to be false")
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if !errors.Is(evidence.Validate(nil), ErrEmptyKRN) || evidence.Matches(nil) {
+			t.Error("expected nil KRN to be rejected")
+		}
+	})
+
+	t.Run("empty shape", func(t *testing.T) {
+		if NewShape().Matches(MustParse("//kopexa.com/frameworks/iso27001")) {
+			t.Error("expected an empty shape to match nothing")
+		}
+	})
+
+	t.Run("does not alias input", func(t *testing.T) {
+		collections := []string{"frameworks", "controls"}
+		s := NewShape(collections...)
+		collections[0] = "changed"
+		s.Collections()[1] = "changed"
+		if s.String() != "frameworks/*/controls/*" {
+			t.Errorf("shape was modified: %s", s)
+		}
+	})
+}
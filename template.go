@@ -0,0 +1,198 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template builds KRNs from a KRN string with placeholders in place of resource
+// IDs, such as //kopexa.com/tenants/{tenant}/workspaces/{workspace}.
+//
+// Templates use the KRN syntax with the default Domain. Collections, the
+// service and the optional version are literal. A resource ID is either a
+// literal or a placeholder "{name}" that is substituted by Expand; a
+// placeholder may appear more than once.
+type Template struct {
+	service  string
+	segments []Segment // ResourceID is a literal, or a placeholder name when isVar is set
+	isVar    []bool
+	version  string
+	sanitize bool
+}
+
+// TemplateOption configures ParseTemplate.
+type TemplateOption func(*Template)
+
+// WithSanitizedValues makes Expand pass every substituted value through
+// SafeResourceID before validating it, e.g. for values taken from user input
+// such as names or email addresses. By default values must already be valid
+// resource IDs.
+func WithSanitizedValues() TemplateOption {
+	return func(t *Template) {
+		t.sanitize = true
+	}
+}
+
+// ParseTemplate parses a template string. See Template for the syntax.
+// Returns ErrEmptyKRN for empty input, ErrInvalidKRN for malformed templates
+// (including empty or malformed placeholders), ErrInvalidDomain for an invalid
+// host, ErrInvalidResourceID for invalid literal resource IDs and
+// ErrInvalidVersion for an invalid version.
+func ParseTemplate(s string, opts ...TemplateOption) (*Template, error) {
+	if s == "" {
+		return nil, ErrEmptyKRN
+	}
+	if !strings.HasPrefix(s, "//") {
+		return nil, fmt.Errorf("%w: template must start with //", ErrInvalidKRN)
+	}
+
+	t := &Template{}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	rest := s[2:]
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest, t.version = rest[:i], rest[i+1:]
+		if !IsValidVersion(t.version) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidVersion, t.version)
+		}
+	}
+
+	parts := strings.Split(rest, "/")
+	service, err := parseHost(parts[0], Domain)
+	if err != nil {
+		return nil, err
+	}
+	t.service = service
+
+	path := parts[1:]
+	if len(path) == 0 || len(path)%2 != 0 {
+		return nil, fmt.Errorf("%w: template path must be pairs of collection/id", ErrInvalidKRN)
+	}
+
+	for i := 0; i < len(path); i += 2 {
+		seg, isVar, err := parseTemplateSegment(path[i], path[i+1])
+		if err != nil {
+			return nil, err
+		}
+		t.segments = append(t.segments, seg)
+		t.isVar = append(t.isVar, isVar)
+	}
+
+	return t, nil
+}
+
+// parseTemplateSegment parses one collection/id pair of a template. For a
+// placeholder, the returned segment holds the placeholder name as ResourceID.
+func parseTemplateSegment(collection, resourceID string) (Segment, bool, error) {
+	if collection == "" || strings.ContainsAny(collection, "{}") {
+		return Segment{}, false, fmt.Errorf("%w: invalid template collection %q", ErrInvalidKRN, collection)
+	}
+
+	if !strings.ContainsAny(resourceID, "{}") {
+		if !IsValidResourceID(resourceID) {
+			return Segment{}, false, fmt.Errorf("%w: %s", ErrInvalidResourceID, resourceID)
+		}
+		return Segment{Collection: collection, ResourceID: resourceID}, false, nil
+	}
+
+	name, ok := strings.CutPrefix(resourceID, "{")
+	if ok {
+		name, ok = strings.CutSuffix(name, "}")
+	}
+	if !ok || name == "" || strings.ContainsAny(name, "{}") {
+		return Segment{}, false, fmt.Errorf("%w: invalid template placeholder %q", ErrInvalidKRN, resourceID)
+	}
+	return Segment{Collection: collection, ResourceID: name}, true, nil
+}
+
+// MustParseTemplate parses a template string and panics on error.
+func MustParseTemplate(s string, opts ...TemplateOption) *Template {
+	t, err := ParseTemplate(s, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Placeholders returns the names of the template's placeholders in path order,
+// each name once.
+func (t *Template) Placeholders() []string {
+	var names []string
+	seen := make(map[string]struct{}, len(t.segments))
+	for i, seg := range t.segments {
+		if !t.isVar[i] {
+			continue
+		}
+		if _, ok := seen[seg.ResourceID]; ok {
+			continue
+		}
+		seen[seg.ResourceID] = struct{}{}
+		names = append(names, seg.ResourceID)
+	}
+	return names
+}
+
+// Expand returns the KRN with every placeholder replaced by its value in vars.
+// Values for names that are not placeholders are ignored. With
+// WithSanitizedValues, each value is passed through SafeResourceID first.
+// Returns an error wrapping ErrInvalidResourceID if a placeholder has no value
+// or its value is not a valid resource ID.
+func (t *Template) Expand(vars map[string]string) (*KRN, error) {
+	segments := make([]Segment, len(t.segments))
+	for i, seg := range t.segments {
+		if !t.isVar[i] {
+			segments[i] = seg
+			continue
+		}
+
+		value, ok := vars[seg.ResourceID]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing value for {%s}", ErrInvalidResourceID, seg.ResourceID)
+		}
+		if t.sanitize {
+			value = SafeResourceID(value)
+		}
+		if !IsValidResourceID(value) {
+			return nil, fmt.Errorf("%w: invalid value %q for {%s}", ErrInvalidResourceID, value, seg.ResourceID)
+		}
+		segments[i] = Segment{Collection: seg.Collection, ResourceID: value}
+	}
+
+	return &KRN{
+		service:  t.service,
+		segments: segments,
+		version:  t.version,
+	}, nil
+}
+
+// String returns the canonical template string.
+func (t *Template) String() string {
+	var sb strings.Builder
+	sb.WriteString("//")
+	if t.service != "" {
+		sb.WriteString(t.service)
+		sb.WriteString(".")
+	}
+	sb.WriteString(Domain)
+
+	for i, seg := range t.segments {
+		sb.WriteString("/")
+		sb.WriteString(seg.Collection)
+		sb.WriteString("/")
+		if t.isVar[i] {
+			sb.WriteString("{" + seg.ResourceID + "}")
+		} else {
+			sb.WriteString(seg.ResourceID)
+		}
+	}
+	if t.version != "" {
+		sb.WriteString("@")
+		sb.WriteString(t.version)
+	}
+	return sb.String()
+}
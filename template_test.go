@@ -0,0 +1,143 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"placeholders", "//kopexa.com/tenants/{tenant}/workspaces/{workspace}", nil},
+		{"service, literal and version", "//isms.kopexa.com/frameworks/iso27001/controls/{control}@v1", nil},
+		{"repeated placeholder", "//kopexa.com/tenants/{id}/mirrors/{id}", nil},
+		{"empty", "", ErrEmptyKRN},
+		{"missing prefix", "kopexa.com/tenants/{tenant}", ErrInvalidKRN},
+		{"wrong domain", "//example.com/tenants/{tenant}", ErrInvalidDomain},
+		{"no path", "//kopexa.com", ErrInvalidKRN},
+		{"odd path", "//kopexa.com/tenants/{tenant}/workspaces", ErrInvalidKRN},
+		{"placeholder collection", "//kopexa.com/{collection}/acme", ErrInvalidKRN},
+		{"empty collection", "//kopexa.com//{id}", ErrInvalidKRN},
+		{"empty placeholder", "//kopexa.com/tenants/{}", ErrInvalidKRN},
+		{"unclosed placeholder", "//kopexa.com/tenants/{tenant", ErrInvalidKRN},
+		{"partial placeholder", "//kopexa.com/tenants/t-{tenant}", ErrInvalidKRN},
+		{"nested placeholder", "//kopexa.com/tenants/{{tenant}}", ErrInvalidKRN},
+		{"invalid literal ID", "//kopexa.com/tenants/-acme", ErrInvalidResourceID},
+		{"invalid version", "//kopexa.com/tenants/{tenant}@v1!", ErrInvalidVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := tmpl.String(); got != tt.input {
+				t.Errorf("String() = %q, want %q", got, tt.input)
+			}
+		})
+	}
+}
+
+func TestMustParseTemplate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	MustParseTemplate("//kopexa.com/tenants/{}")
+}
+
+func TestTemplate_Placeholders(t *testing.T) {
+	tmpl := MustParseTemplate("//kopexa.com/tenants/{tenant}/frameworks/iso27001/mirrors/{tenant}/workspaces/{workspace}")
+	if got, want := tmpl.Placeholders(), []string{"tenant", "workspace"}; !slices.Equal(got, want) {
+		t.Errorf("Placeholders() = %v, want %v", got, want)
+	}
+
+	if got := MustParseTemplate("//kopexa.com/frameworks/iso27001").Placeholders(); got != nil {
+		t.Errorf("expected no placeholders, got %v", got)
+	}
+}
+
+func TestTemplate_Expand(t *testing.T) {
+	tmpl := MustParseTemplate("//isms.kopexa.com/tenants/{tenant}/frameworks/iso27001/workspaces/{workspace}@v1")
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "all values",
+			vars: map[string]string{"tenant": "acme", "workspace": "main"},
+			want: "//isms.kopexa.com/tenants/acme/frameworks/iso27001/workspaces/main@v1",
+		},
+		{
+			name: "extra values are ignored",
+			vars: map[string]string{"tenant": "acme", "workspace": "main", "other": "x"},
+			want: "//isms.kopexa.com/tenants/acme/frameworks/iso27001/workspaces/main@v1",
+		},
+		{name: "missing value", vars: map[string]string{"tenant": "acme"}, wantErr: ErrInvalidResourceID},
+		{name: "nil vars", vars: nil, wantErr: ErrInvalidResourceID},
+		{name: "empty value", vars: map[string]string{"tenant": "", "workspace": "main"}, wantErr: ErrInvalidResourceID},
+		{name: "invalid value", vars: map[string]string{"tenant": "acme/other", "workspace": "main"}, wantErr: ErrInvalidResourceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tmpl.Expand(tt.vars)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Expand() = %s, want %s", got, tt.want)
+			}
+			if !got.EqualsString(tt.want) {
+				t.Error("expected expanded KRN to equal the parsed string")
+			}
+		})
+	}
+
+	t.Run("repeated placeholder", func(t *testing.T) {
+		got, err := MustParseTemplate("//kopexa.com/tenants/{id}/mirrors/{id}").Expand(map[string]string{"id": "acme"})
+		if err != nil || got.String() != "//kopexa.com/tenants/acme/mirrors/acme" {
+			t.Errorf("Expand() = %v, %v", got, err)
+		}
+	})
+}
+
+func TestTemplate_ExpandSanitized(t *testing.T) {
+	tmpl := MustParseTemplate("//kopexa.com/tenants/{tenant}/users/{user}", WithSanitizedValues())
+
+	got, err := tmpl.Expand(map[string]string{"tenant": "Acme Corp", "user": "jane@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "//kopexa.com/tenants/Acme-Corp/users/jane-example.com"; got.String() != want {
+		t.Errorf("Expand() = %s, want %s", got, want)
+	}
+
+	if _, err := tmpl.Expand(map[string]string{"tenant": "!!!", "user": "jane"}); !errors.Is(err, ErrInvalidResourceID) {
+		t.Errorf("expected ErrInvalidResourceID for a value that sanitizes to empty, got %v", err)
+	}
+}
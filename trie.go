@@ -0,0 +1,79 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+// Trie maps KRN prefixes to values for longest-prefix lookups, e.g. a routing
+// table where //kopexa.com/tenants/acme routes every resource of that tenant.
+//
+// Keys are segment sequences (collection and resource ID pairs); the service,
+// domain, version and query are ignored, so KRNs with the same segments share
+// one entry. A lookup walks the KRN's segments once, independent of the number
+// of entries. The zero value is an empty trie ready to use. A Trie is not safe
+// for concurrent writes; build it once and share it read-only.
+type Trie struct {
+	root trieNode
+	n    int
+}
+
+// trieNode is a node of a Trie. key is the KRN inserted at the node, or nil if
+// the node is only an intermediate step.
+type trieNode struct {
+	children map[Segment]*trieNode
+	key      *KRN
+	value    any
+}
+
+// Insert associates value with the segments of k, replacing the value of a
+// previous KRN with the same segments. A nil k is ignored.
+func (t *Trie) Insert(k *KRN, value any) {
+	if k == nil {
+		return
+	}
+
+	node := &t.root
+	for _, seg := range k.segments {
+		child, ok := node.children[seg]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[Segment]*trieNode)
+			}
+			child = &trieNode{}
+			node.children[seg] = child
+		}
+		node = child
+	}
+
+	if node.key == nil {
+		t.n++
+	}
+	node.key = k
+	node.value = value
+}
+
+// LongestPrefixMatch returns the value of the inserted KRN whose segments are
+// the longest prefix of k's segments (k itself included), and that KRN as
+// inserted. ok is false if no inserted KRN is a prefix of k or k is nil.
+func (t *Trie) LongestPrefixMatch(k *KRN) (value any, matched *KRN, ok bool) {
+	if k == nil {
+		return nil, nil, false
+	}
+
+	node := &t.root
+	for _, seg := range k.segments {
+		child, found := node.children[seg]
+		if !found {
+			break
+		}
+		node = child
+		if node.key != nil {
+			value, matched, ok = node.value, node.key, true
+		}
+	}
+	return value, matched, ok
+}
+
+// Len returns the number of entries in the trie.
+func (t *Trie) Len() int {
+	return t.n
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTrie(t *testing.T) {
+	var routes Trie
+	routes.Insert(MustParse("//kopexa.com/tenants/acme"), "acme")
+	routes.Insert(MustParse("//kopexa.com/tenants/acme/workspaces/main@v1"), "main")
+	routes.Insert(MustParse("//kopexa.com/frameworks/iso27001"), "iso")
+	routes.Insert(nil, "ignored")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		matched string
+	}{
+		{"exact", "//kopexa.com/tenants/acme", "acme", "//kopexa.com/tenants/acme"},
+		{"descendant of shorter prefix", "//kopexa.com/tenants/acme/workspaces/dev", "acme", "//kopexa.com/tenants/acme"},
+		{"longest prefix wins", "//kopexa.com/tenants/acme/workspaces/main/evidences/ev-1", "main", "//kopexa.com/tenants/acme/workspaces/main@v1"},
+		{"service and version ignored", "//isms.kopexa.com/frameworks/iso27001/controls/a-5-1@v2", "iso", "//kopexa.com/frameworks/iso27001"},
+		{"no match", "//kopexa.com/tenants/globex", nil, ""},
+		{"intermediate node only", "//kopexa.com/tenants/acme/workspaces/main", "main", "//kopexa.com/tenants/acme/workspaces/main@v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, matched, ok := routes.LongestPrefixMatch(MustParse(tt.input))
+			if ok != (tt.want != nil) || value != tt.want {
+				t.Fatalf("LongestPrefixMatch() = (%v, %v, %v), want %v", value, matched, ok, tt.want)
+			}
+			if ok && matched.String() != tt.matched {
+				t.Errorf("matched = %s, want %s", matched, tt.matched)
+			}
+		})
+	}
+
+	t.Run("len and replace", func(t *testing.T) {
+		if routes.Len() != 3 {
+			t.Errorf("Len() = %d, want 3", routes.Len())
+		}
+		routes.Insert(MustParse("//catalog.kopexa.com/tenants/acme"), "replaced")
+		if routes.Len() != 3 {
+			t.Errorf("Len() after replace = %d, want 3", routes.Len())
+		}
+		if value, matched, _ := routes.LongestPrefixMatch(MustParse("//kopexa.com/tenants/acme/users/u-1")); value != "replaced" || !matched.HasService() {
+			t.Errorf("expected the replaced entry, got (%v, %v)", value, matched)
+		}
+	})
+
+	t.Run("nil and empty", func(t *testing.T) {
+		if _, _, ok := routes.LongestPrefixMatch(nil); ok {
+			t.Error("expected no match for nil")
+		}
+		var empty Trie
+		if _, _, ok := empty.LongestPrefixMatch(MustParse("//kopexa.com/tenants/acme")); ok || empty.Len() != 0 {
+			t.Error("expected an empty trie to match nothing")
+		}
+	})
+}
+
+func BenchmarkLongestPrefixMatch(b *testing.B) {
+	const tenants = 1000
+	var routes Trie
+	patterns := make([]*Pattern, 0, 2*tenants)
+	for i := range tenants {
+		for _, s := range []string{
+			fmt.Sprintf("//kopexa.com/tenants/t-%d", i),
+			fmt.Sprintf("//kopexa.com/tenants/t-%d/workspaces/main", i),
+		} {
+			routes.Insert(MustParse(s), i)
+			patterns = append(patterns, MustParsePattern(s+"/**"))
+		}
+	}
+	k := MustParse(fmt.Sprintf("//kopexa.com/tenants/t-%d/workspaces/main/evidences/ev-1", tenants-1))
+
+	b.Run("trie", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			routes.LongestPrefixMatch(k)
+		}
+	})
+
+	// The linear scan of "prefix/**" patterns that the trie replaces.
+	b.Run("linear patterns", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var best *Pattern
+			for _, p := range patterns {
+				if p.Match(k) && (best == nil || len(p.segments) > len(best.segments)) {
+					best = p
+				}
+			}
+		}
+	})
+}
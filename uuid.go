@@ -0,0 +1,32 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"crypto/sha1" //nolint:gosec // UUIDv5 is defined over SHA-1 (RFC 9562); it is not used for security.
+)
+
+// UUID returns a name-based (version 5) UUID derived from the canonical string
+// of the KRN within the given namespace, as defined in RFC 9562.
+//
+// Equal KRNs always yield the same UUID and different KRNs yield different UUIDs.
+// The version is part of the canonical string, so //kopexa.com/frameworks/iso27001@v1
+// and //kopexa.com/frameworks/iso27001@v2 map to distinct UUIDs.
+//
+// The namespace and result are plain [16]byte values so the package stays free of
+// dependencies; github.com/google/uuid.UUID values can be passed and assigned directly.
+func (k *KRN) UUID(namespace [16]byte) [16]byte {
+	return uuidV5(namespace, k.String())
+}
+
+// uuidV5 computes an RFC 9562 version 5 UUID for name within namespace.
+func uuidV5(namespace [16]byte, name string) [16]byte {
+	sum := sha1.Sum(append(namespace[:], name...)) //nolint:gosec // see import comment
+
+	var u [16]byte
+	copy(u[:], sum[:])
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return u
+}
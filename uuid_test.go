@@ -0,0 +1,52 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// namespaceDNS is the RFC 9562 DNS namespace (6ba7b810-9dad-11d1-80b4-00c04fd430c8).
+var namespaceDNS = [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+func TestUUIDV5_KnownVector(t *testing.T) {
+	got := uuidV5(namespaceDNS, "www.example.com")
+	want := "2ed6657de927568b95e12665a8aea6a2"
+	if hex.EncodeToString(got[:]) != want {
+		t.Errorf("uuidV5() = %x, want %s", got, want)
+	}
+}
+
+func TestKRN_UUID(t *testing.T) {
+	a := MustParse("//kopexa.com/frameworks/iso27001")
+	b := MustParse("//kopexa.com/frameworks/iso27001")
+	c := MustParse("//kopexa.com/frameworks/iso27001@v1")
+	d := MustParse("//catalog.kopexa.com/frameworks/iso27001")
+
+	ua := a.UUID(namespaceDNS)
+
+	if ua != b.UUID(namespaceDNS) {
+		t.Error("expected equal KRNs to yield equal UUIDs")
+	}
+	if ua == c.UUID(namespaceDNS) {
+		t.Error("expected version to change the UUID")
+	}
+	if ua == d.UUID(namespaceDNS) {
+		t.Error("expected service to change the UUID")
+	}
+	if ua == a.UUID([16]byte{}) {
+		t.Error("expected namespace to change the UUID")
+	}
+	if ua != uuidV5(namespaceDNS, a.String()) {
+		t.Error("expected UUID to be computed over the canonical string")
+	}
+
+	if v := ua[6] >> 4; v != 5 {
+		t.Errorf("expected version 5, got %d", v)
+	}
+	if variant := ua[8] >> 6; variant != 0b10 {
+		t.Errorf("expected RFC 9562 variant, got %b", variant)
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VersionChannel is a named floating version, such as "draft" or "latest",
+// that points at whatever release is current in that channel instead of a
+// fixed release.
+//
+// Rank orders channels relative to each other and to concrete versions:
+// channels with a negative rank sort below every concrete version, channels
+// with a positive rank above, and a higher rank sorts later.
+type VersionChannel struct {
+	Name string
+	Rank int
+}
+
+// Built-in version channels.
+var (
+	ChannelDraft  = VersionChannel{Name: "draft", Rank: -100}
+	ChannelLatest = VersionChannel{Name: "latest", Rank: 100}
+)
+
+// versionChannels is the registry of known channels, keyed by name.
+var (
+	versionChannelsMu sync.RWMutex
+	versionChannels   = map[string]VersionChannel{
+		ChannelDraft.Name:  ChannelDraft,
+		ChannelLatest.Name: ChannelLatest,
+	}
+)
+
+// RegisterVersionChannel adds a version channel, or changes the rank of an
+// existing one, e.g. to order draft < beta < stable < latest:
+//
+//	krn.RegisterVersionChannel(krn.VersionChannel{Name: "beta", Rank: 50})
+//	krn.RegisterVersionChannel(krn.VersionChannel{Name: "stable", Rank: 80})
+//
+// The name must be a valid version that is not a semantic version, and the
+// rank must not be zero. Otherwise ErrInvalidVersion is returned. Channels are
+// global; register them during program initialization.
+func RegisterVersionChannel(c VersionChannel) error {
+	if !IsValidVersion(c.Name) {
+		return fmt.Errorf("%w: invalid channel name %q", ErrInvalidVersion, c.Name)
+	}
+	if _, ok := parseSemver(c.Name); ok {
+		return fmt.Errorf("%w: channel name %q is a semantic version", ErrInvalidVersion, c.Name)
+	}
+	if c.Rank == 0 {
+		return fmt.Errorf("%w: channel %q must have a non-zero rank", ErrInvalidVersion, c.Name)
+	}
+
+	versionChannelsMu.Lock()
+	defer versionChannelsMu.Unlock()
+	versionChannels[c.Name] = c
+	return nil
+}
+
+// VersionChannels returns the registered version channels in ascending rank order.
+func VersionChannels() []VersionChannel {
+	versionChannelsMu.RLock()
+	defer versionChannelsMu.RUnlock()
+
+	channels := slices.Collect(maps.Values(versionChannels))
+	slices.SortFunc(channels, func(a, b VersionChannel) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), strings.Compare(a.Name, b.Name))
+	})
+	return channels
+}
+
+// LookupVersionChannel returns the registered channel named v, if any.
+func LookupVersionChannel(v string) (VersionChannel, bool) {
+	versionChannelsMu.RLock()
+	defer versionChannelsMu.RUnlock()
+	c, ok := versionChannels[v]
+	return c, ok
+}
+
+// isFloatingVersion reports whether v is a registered version channel.
+func isFloatingVersion(v string) bool {
+	_, ok := LookupVersionChannel(v)
+	return ok
+}
+
+// semver is the numeric subset of versions accepted by IsValidVersion:
+// an optional "v" prefix followed by one to three dot-separated numbers.
+// Missing components are treated as zero (v1 == v1.0 == v1.0.0).
+type semver struct {
+	major, minor, patch int
+	parts               int // number of components present in the source string
+}
+
+// parseSemver parses s as a semantic version. It returns false for
+// keywords such as "latest" and for non-numeric versions such as "2022-01-15".
+func parseSemver(s string) (semver, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return semver{}, false
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return semver{}, false
+	}
+
+	var nums [3]int
+	for i, f := range fields {
+		if f == "" || strings.TrimLeft(f, "0123456789") != "" {
+			return semver{}, false
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], parts: len(fields)}, true
+}
+
+// compare returns -1, 0, or +1 depending on whether v sorts before, equal to, or after o.
+func (v semver) compare(o semver) int {
+	return cmp.Or(
+		cmp.Compare(v.major, o.major),
+		cmp.Compare(v.minor, o.minor),
+		cmp.Compare(v.patch, o.patch),
+	)
+}
+
+// versionRank groups versions for ordering: unversioned < negative-rank channels
+// (e.g. draft) < semantic versions < other concrete versions (e.g. dates) <
+// positive-rank channels (e.g. latest). Within a channel group, the second
+// result is the channel rank.
+func versionRank(v string) (group, rank int) {
+	if v == "" {
+		return 0, 0
+	}
+	if c, ok := LookupVersionChannel(v); ok {
+		if c.Rank < 0 {
+			return 1, c.Rank
+		}
+		return 4, c.Rank
+	}
+	if _, ok := parseSemver(v); ok {
+		return 2, 0
+	}
+	return 3, 0
+}
+
+// compareRanks compares the versionRank of a and b.
+func compareRanks(a, b string) int {
+	ga, ra := versionRank(a)
+	gb, rb := versionRank(b)
+	return cmp.Or(cmp.Compare(ga, gb), cmp.Compare(ra, rb))
+}
+
+// compareVersions orders any two versions accepted by IsValidVersion (or empty).
+// Semantic versions compare numerically, other concrete versions compare
+// lexically, and versionRank separates the groups and orders channels.
+func compareVersions(a, b string) int {
+	if c := compareRanks(a, b); c != 0 {
+		return c
+	}
+
+	if va, ok := parseSemver(a); ok {
+		vb, _ := parseSemver(b)
+		if c := va.compare(vb); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// CompareVersions compares two versions and returns -1, 0 or +1.
+//
+// It understands the semantic versions accepted by IsValidVersion (v1, v1.2,
+// v1.2.3, with or without the "v" prefix), compared numerically so that
+// v1.2.3 < v1.10.0. Missing components count as zero, so v1 and v1.0.0 are
+// equal. Version channels (see VersionChannel) compare by rank: by default
+// "draft" sorts below every semantic version and "latest" above.
+// Any other version, such as "2022-01-15" or an unregistered channel, or an
+// empty version returns ErrInvalidVersion.
+func CompareVersions(a, b string) (int, error) {
+	for _, v := range []string{a, b} {
+		if _, ok := parseSemver(v); !ok && !isFloatingVersion(v) {
+			return 0, fmt.Errorf("%w: %q is neither a semantic version nor a version channel", ErrInvalidVersion, v)
+		}
+	}
+
+	va, okA := parseSemver(a)
+	vb, okB := parseSemver(b)
+	if okA && okB {
+		return va.compare(vb), nil
+	}
+	return compareRanks(a, b), nil
+}
+
+// VersionLess reports whether k's version sorts before other's according to
+// CompareVersions. It returns false if other is nil or either version cannot
+// be compared (unversioned KRNs or non-semantic versions).
+func (k *KRN) VersionLess(other *KRN) bool {
+	if other == nil {
+		return false
+	}
+	c, err := CompareVersions(k.version, other.version)
+	return err == nil && c < 0
+}
+
+// versionConstraint is a single comparator of a constraint expression, e.g. ">=v1.2.0".
+type versionConstraint struct {
+	op      string
+	version semver
+}
+
+// constraintOps lists the supported operators, longest first so that ">=" wins over ">".
+var constraintOps = []string{">=", "<=", ">", "<", "=", "~", "^"}
+
+// parseConstraint parses a whitespace-separated list of comparators.
+func parseConstraint(constraint string) ([]versionConstraint, error) {
+	fields := strings.Fields(constraint)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty constraint", ErrInvalidConstraint)
+	}
+
+	terms := make([]versionConstraint, 0, len(fields))
+	for _, f := range fields {
+		op := "="
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(f, candidate) {
+				op = candidate
+				f = f[len(candidate):]
+				break
+			}
+		}
+
+		v, ok := parseSemver(f)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a semantic version", ErrInvalidConstraint, f)
+		}
+		terms = append(terms, versionConstraint{op: op, version: v})
+	}
+
+	return terms, nil
+}
+
+// matches reports whether v satisfies the comparator.
+func (c versionConstraint) matches(v semver) bool {
+	order := v.compare(c.version)
+	switch c.op {
+	case ">=":
+		return order >= 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case "<":
+		return order < 0
+	case "~":
+		return order >= 0 && v.compare(c.tildeUpper()) < 0
+	case "^":
+		return order >= 0 && v.compare(c.caretUpper()) < 0
+	default:
+		return order == 0
+	}
+}
+
+// tildeUpper returns the exclusive upper bound of a "~" comparator:
+// ~v1.2.3 and ~v1.2 allow patch updates (<v1.3.0), ~v1 allows minor updates (<v2.0.0).
+func (c versionConstraint) tildeUpper() semver {
+	if c.version.parts == 1 {
+		return semver{major: c.version.major + 1}
+	}
+	return semver{major: c.version.major, minor: c.version.minor + 1}
+}
+
+// caretUpper returns the exclusive upper bound of a "^" comparator:
+// the left-most non-zero component may not change (^v1.2.3 <v2.0.0, ^v0.2.3 <v0.3.0, ^v0.0.3 <v0.0.4).
+func (c versionConstraint) caretUpper() semver {
+	v := c.version
+	switch {
+	case v.major != 0 || v.parts == 1:
+		return semver{major: v.major + 1}
+	case v.minor != 0 || v.parts == 2:
+		return semver{minor: v.minor + 1}
+	default:
+		return semver{patch: v.patch + 1}
+	}
+}
+
+// SplitVersion splits a raw KRN string at its last "@" into the unversioned
+// base and the version, without parsing or validating either part.
+// ok is false and base is s if s has no "@".
+func SplitVersion(s string) (base, version string, ok bool) {
+	idx := strings.LastIndex(s, "@")
+	if idx == -1 {
+		return s, "", false
+	}
+	return s[:idx], s[idx+1:], true
+}
+
+// JoinVersion appends "@version" to an unversioned KRN string, e.g. when the
+// path and the version arrive in separate request fields. An empty version
+// returns base unchanged. The base is not parsed; it must not already contain
+// a version. Returns ErrInvalidVersion if version is not valid and
+// ErrInvalidKRN if base contains "@".
+func JoinVersion(base, version string) (string, error) {
+	if strings.Contains(base, "@") {
+		return "", fmt.Errorf("%w: %s already has a version", ErrInvalidKRN, base)
+	}
+	if version == "" {
+		return base, nil
+	}
+	if !IsValidVersion(version) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidVersion, version)
+	}
+	return base + "@" + version, nil
+}
+
+// VersionAny is a version pattern that matches every version, including none.
+// It can be used as the version of a Pattern ("//kopexa.com/frameworks/iso27001@*")
+// and with MatchesVersion.
+const VersionAny = "*"
+
+// MatchesVersion reports whether version matches the version pattern: VersionAny
+// matches every version, including the empty version of unversioned KRNs; any
+// other pattern matches only that exact version, so "" matches only
+// unversioned KRNs.
+func MatchesVersion(pattern, version string) bool {
+	return pattern == VersionAny || pattern == version
+}
+
+// VersionSatisfies reports whether the KRN's version satisfies a constraint expression.
+//
+// A constraint is a whitespace-separated list of comparators that must all hold,
+// e.g. ">=v1.2.0 <v2.0.0". Supported operators are >=, >, <=, <, = (the default
+// when no operator is given), ~ (patch-level updates, or minor-level for ~v1) and
+// ^ (updates that keep the left-most non-zero component).
+//
+// Only semantic versions (v1, v1.2, v1.2.3, with or without the "v" prefix) can
+// satisfy a constraint. Unversioned KRNs and non-numeric versions such as
+// "latest", "draft" or "2022-01-15" return false without an error.
+// A malformed constraint returns ErrInvalidConstraint.
+func (k *KRN) VersionSatisfies(constraint string) (bool, error) {
+	terms, err := parseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+
+	v, ok := parseSemver(k.version)
+	if !ok {
+		return false, nil
+	}
+
+	for _, term := range terms {
+		if !term.matches(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SortByVersion sorts ks in place by version alone, e.g. to list the versions
+// of one resource: unversioned < "draft" < semantic versions (numerically) <
+// other versions (lexically) < "latest", with other version channels placed by
+// rank (see VersionChannel). KRNs with equal versions keep their relative
+// order; nil entries sort first.
+func SortByVersion(ks []*KRN) {
+	slices.SortStableFunc(ks, func(a, b *KRN) int {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		case b == nil:
+			return 1
+		}
+		return compareVersions(a.version, b.version)
+	})
+}
+
+// ParseResolved parses a KRN string like Parse and pins floating versions.
+//
+// If the parsed version is a version channel such as "latest" or "draft"
+// (see VersionChannel), resolve is called with the unversioned KRN and must
+// return the concrete version to pin. Errors returned by resolve are propagated
+// unchanged. A resolved version that is invalid or itself
+// floating returns ErrInvalidVersion. KRNs without a floating version are returned
+// as parsed and resolve is not called.
+func ParseResolved(s string, resolve func(base *KRN) (string, error)) (*KRN, error) {
+	k, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if !isFloatingVersion(k.version) {
+		return k, nil
+	}
+
+	version, err := resolve(k.WithoutVersion())
+	if err != nil {
+		return nil, err
+	}
+	if isFloatingVersion(version) {
+		return nil, fmt.Errorf("%w: resolved version %s is not pinned", ErrInvalidVersion, version)
+	}
+	return k.WithVersion(version)
+}
+
+// VersionDiff compares k with another KRN for the same resource.
+//
+// If both KRNs share the same base domain, service and segment path, sameResource is true and
+// older and newer hold the two KRNs ordered by version: unversioned < "draft" <
+// semantic versions (compared numerically) < other versions (compared lexically)
+// < "latest". If the versions are equal, older is k and newer is other.
+// If the KRNs denote different resources, or other is nil, sameResource is false
+// and older and newer are nil.
+func (k *KRN) VersionDiff(other *KRN) (older, newer *KRN, sameResource bool) {
+	if other == nil || !sameHost(k, other) || !sameSegments(k.segments, other.segments) {
+		return nil, nil, false
+	}
+
+	if compareVersions(k.version, other.version) > 0 {
+		return other, k, true
+	}
+	return k, other, true
+}
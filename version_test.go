@@ -0,0 +1,556 @@
+// Copyright (c) Kopexa GRC
+// SPDX-License-Identifier: Apache-2.0
+
+package krn
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		input string
+		want  semver
+		ok    bool
+	}{
+		{"v1", semver{major: 1, parts: 1}, true},
+		{"v1.2", semver{major: 1, minor: 2, parts: 2}, true},
+		{"v1.2.3", semver{major: 1, minor: 2, patch: 3, parts: 3}, true},
+		{"1.0.0", semver{major: 1, parts: 3}, true},
+		{"2022", semver{major: 2022, parts: 1}, true},
+		{"v1.2.3.4", semver{}, false},
+		{"latest", semver{}, false},
+		{"draft", semver{}, false},
+		{"2022-01-15", semver{}, false},
+		{"v1.x", semver{}, false},
+		{"v", semver{}, false},
+		{"", semver{}, false},
+		{"v1..2", semver{}, false},
+		{"v99999999999999999999", semver{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseSemver(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("parseSemver(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("parseSemver(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersionsOrder(t *testing.T) {
+	// Ascending order; every element must compare below all following ones.
+	ordered := []string{
+		"",
+		"draft",
+		"0.9",
+		"v1",
+		"v1.0.1",
+		"v1.2",
+		"1.10.0",
+		"v2.0.0",
+		"2022",
+		"2022-01-15",
+		"2023-01-01",
+		"rev1",
+		"latest",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			got := compareVersions(ordered[i], ordered[j])
+			want := cmp.Compare(i, j)
+			if got != want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	// Numerically equal spellings fall back to a lexical tie-break for a total order.
+	equal := [][2]string{
+		{"v1", "v1.0.0"},
+		{"1.2", "v1.2.0"},
+	}
+	for _, pair := range equal {
+		if got, want := compareVersions(pair[0], pair[1]), strings.Compare(pair[0], pair[1]); got != want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", pair[0], pair[1], got, want)
+		}
+	}
+}
+
+// restoreVersionChannels resets the channel registry after a test.
+func restoreVersionChannels(t *testing.T) {
+	t.Helper()
+	versionChannelsMu.RLock()
+	saved := maps.Clone(versionChannels)
+	versionChannelsMu.RUnlock()
+	t.Cleanup(func() {
+		versionChannelsMu.Lock()
+		versionChannels = saved
+		versionChannelsMu.Unlock()
+	})
+}
+
+func TestVersionChannels(t *testing.T) {
+	restoreVersionChannels(t)
+
+	if got := VersionChannels(); !slices.Equal(got, []VersionChannel{ChannelDraft, ChannelLatest}) {
+		t.Fatalf("default VersionChannels() = %v", got)
+	}
+	if _, err := CompareVersions("stable", "v1"); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("expected ErrInvalidVersion for an unregistered channel, got %v", err)
+	}
+
+	beta := VersionChannel{Name: "beta", Rank: 50}
+	stable := VersionChannel{Name: "stable", Rank: 80}
+	for _, c := range []VersionChannel{stable, beta} {
+		if err := RegisterVersionChannel(c); err != nil {
+			t.Fatalf("RegisterVersionChannel(%v): %v", c, err)
+		}
+	}
+	if got := VersionChannels(); !slices.Equal(got, []VersionChannel{ChannelDraft, beta, stable, ChannelLatest}) {
+		t.Errorf("VersionChannels() = %v", got)
+	}
+	if c, ok := LookupVersionChannel("beta"); !ok || c != beta {
+		t.Errorf("LookupVersionChannel(beta) = (%v, %v)", c, ok)
+	}
+	if _, ok := LookupVersionChannel("v1"); ok {
+		t.Error("v1 is not a channel")
+	}
+
+	// draft < v1 < v2 < beta < stable < latest
+	ordered := []string{"draft", "v1", "v2", "beta", "stable", "latest"}
+	for i := range ordered {
+		for j := range ordered {
+			got, err := CompareVersions(ordered[i], ordered[j])
+			if err != nil || got != cmp.Compare(i, j) {
+				t.Errorf("CompareVersions(%q, %q) = (%d, %v), want %d", ordered[i], ordered[j], got, err, cmp.Compare(i, j))
+			}
+			if got := compareVersions(ordered[i], ordered[j]); got != cmp.Compare(i, j) {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, cmp.Compare(i, j))
+			}
+		}
+	}
+
+	t.Run("negative rank sorts below releases", func(t *testing.T) {
+		if err := RegisterVersionChannel(VersionChannel{Name: "nightly", Rank: -50}); err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := CompareVersions("nightly", "v0.0.1"); c != -1 {
+			t.Errorf("expected nightly < v0.0.1, got %d", c)
+		}
+		if c, _ := CompareVersions("draft", "nightly"); c != -1 {
+			t.Errorf("expected draft < nightly, got %d", c)
+		}
+	})
+
+	t.Run("re-registering changes rank", func(t *testing.T) {
+		if err := RegisterVersionChannel(VersionChannel{Name: "beta", Rank: 90}); err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := CompareVersions("beta", "stable"); c != 1 {
+			t.Errorf("expected beta > stable after re-registering, got %d", c)
+		}
+	})
+
+	t.Run("channels are floating for ParseResolved", func(t *testing.T) {
+		k, err := ParseResolved("//kopexa.com/frameworks/iso27001@stable", func(*KRN) (string, error) {
+			return "v2.1", nil
+		})
+		if err != nil || k.Version() != "v2.1" {
+			t.Errorf("ParseResolved() = (%v, %v)", k, err)
+		}
+	})
+
+	t.Run("invalid channels", func(t *testing.T) {
+		for _, c := range []VersionChannel{
+			{Name: "", Rank: 1},
+			{Name: "-beta", Rank: 1},
+			{Name: "v2", Rank: 1},
+			{Name: "1.2", Rank: 1},
+			{Name: "beta", Rank: 0},
+		} {
+			if err := RegisterVersionChannel(c); !errors.Is(err, ErrInvalidVersion) {
+				t.Errorf("RegisterVersionChannel(%+v): expected ErrInvalidVersion, got %v", c, err)
+			}
+		}
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{"v1.2.3", "v1.10.0", -1, false},
+		{"v1.10.0", "v1.2.3", 1, false},
+		{"v2", "v1.9.9", 1, false},
+		{"v1", "v1.0.0", 0, false},
+		{"1.2.3", "v1.2.3", 0, false},
+		{"draft", "v0.0.1", -1, false},
+		{"latest", "v99", 1, false},
+		{"draft", "latest", -1, false},
+		{"latest", "latest", 0, false},
+		{"draft", "draft", 0, false},
+		{"2022-01-15", "v1", 0, true},
+		{"v1", "2022-01-15", 0, true},
+		{"", "v1", 0, true},
+		{"v1.2.3.4", "v1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			got, err := CompareVersions(tt.a, tt.b)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidVersion) {
+					t.Errorf("expected ErrInvalidVersion, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		pattern string
+		version string
+		want    bool
+	}{
+		{VersionAny, "v1", true},
+		{VersionAny, "latest", true},
+		{VersionAny, "", true},
+		{"v1", "v1", true},
+		{"v1", "v2", false},
+		{"v1", "", false},
+		{"", "", true},
+		{"", "v1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.version, func(t *testing.T) {
+			if got := MatchesVersion(tt.pattern, tt.version); got != tt.want {
+				t.Errorf("MatchesVersion(%q, %q) = %v, want %v", tt.pattern, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		input, base, version string
+		ok                   bool
+	}{
+		{"//kopexa.com/frameworks/iso27001@v1.2", "//kopexa.com/frameworks/iso27001", "v1.2", true},
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001", "", false},
+		{"//kopexa.com/frameworks/iso27001@", "//kopexa.com/frameworks/iso27001", "", true},
+		{"a@b@c", "a@b", "c", true},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			base, version, ok := SplitVersion(tt.input)
+			if base != tt.base || version != tt.version || ok != tt.ok {
+				t.Errorf("SplitVersion(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.input, base, version, ok, tt.base, tt.version, tt.ok)
+			}
+		})
+	}
+}
+
+func TestJoinVersion(t *testing.T) {
+	tests := []struct {
+		base, version string
+		want          string
+		wantErr       error
+	}{
+		{"//kopexa.com/frameworks/iso27001", "v2", "//kopexa.com/frameworks/iso27001@v2", nil},
+		{"//kopexa.com/frameworks/iso27001", "", "//kopexa.com/frameworks/iso27001", nil},
+		{"//kopexa.com/frameworks/iso27001", "-bad", "", ErrInvalidVersion},
+		{"//kopexa.com/frameworks/iso27001@v1", "v2", "", ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base+"+"+tt.version, func(t *testing.T) {
+			got, err := JoinVersion(tt.base, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("JoinVersion(%q, %q) = %q, want %q", tt.base, tt.version, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		const input = "//catalog.kopexa.com/frameworks/iso27001@2022-01-15"
+		base, version, _ := SplitVersion(input)
+		got, err := JoinVersion(base, version)
+		if err != nil || got != input {
+			t.Errorf("round trip = (%q, %v), want %q", got, err, input)
+		}
+	})
+}
+
+func TestKRN_VersionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"//kopexa.com/frameworks/iso27001@v1.2.3", "//kopexa.com/frameworks/iso27001@v1.10.0", true},
+		{"//kopexa.com/frameworks/iso27001@v1.10.0", "//kopexa.com/frameworks/iso27001@v1.2.3", false},
+		{"//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1.0.0", false},
+		{"//kopexa.com/frameworks/iso27001@draft", "//kopexa.com/frameworks/iso27001@v1", true},
+		{"//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@latest", true},
+		{"//kopexa.com/frameworks/iso27001", "//kopexa.com/frameworks/iso27001@v1", false},
+		{"//kopexa.com/frameworks/iso27001@2022-01-15", "//kopexa.com/frameworks/iso27001@v1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			if got := MustParse(tt.a).VersionLess(MustParse(tt.b)); got != tt.want {
+				t.Errorf("VersionLess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if MustParse("//kopexa.com/frameworks/iso27001@v1").VersionLess(nil) {
+			t.Error("expected false for nil")
+		}
+	})
+}
+
+func TestKRN_VersionSatisfies(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		constraint string
+		want       bool
+		wantErr    error
+	}{
+		{name: "range inside", input: "//kopexa.com/frameworks/iso27001@v1.5.0", constraint: ">=v1.2.0 <v2.0.0", want: true},
+		{name: "range lower bound", input: "//kopexa.com/frameworks/iso27001@v1.2.0", constraint: ">=v1.2.0 <v2.0.0", want: true},
+		{name: "range upper bound", input: "//kopexa.com/frameworks/iso27001@v2.0.0", constraint: ">=v1.2.0 <v2.0.0", want: false},
+		{name: "range below", input: "//kopexa.com/frameworks/iso27001@v1.1.9", constraint: ">=v1.2.0 <v2.0.0", want: false},
+		{name: "numeric not lexical", input: "//kopexa.com/frameworks/iso27001@v1.10.0", constraint: ">v1.9.0", want: true},
+		{name: "greater", input: "//kopexa.com/frameworks/iso27001@v2", constraint: ">v1", want: true},
+		{name: "less or equal", input: "//kopexa.com/frameworks/iso27001@v1.0", constraint: "<=v1", want: true},
+		{name: "equal explicit", input: "//kopexa.com/frameworks/iso27001@v1.2.3", constraint: "=v1.2.3", want: true},
+		{name: "equal implicit", input: "//kopexa.com/frameworks/iso27001@v1.2.3", constraint: "v1.2.3", want: true},
+		{name: "equal missing components", input: "//kopexa.com/frameworks/iso27001@v1", constraint: "=v1.0.0", want: true},
+		{name: "equal without prefix", input: "//kopexa.com/frameworks/iso27001@1.2.3", constraint: "=v1.2.3", want: true},
+		{name: "not equal", input: "//kopexa.com/frameworks/iso27001@v1.2.4", constraint: "=v1.2.3", want: false},
+		{name: "tilde patch update", input: "//kopexa.com/frameworks/iso27001@v1.2.9", constraint: "~v1.2.3", want: true},
+		{name: "tilde minor update", input: "//kopexa.com/frameworks/iso27001@v1.3.0", constraint: "~v1.2.3", want: false},
+		{name: "tilde major only", input: "//kopexa.com/frameworks/iso27001@v1.9.0", constraint: "~v1", want: true},
+		{name: "tilde major only next major", input: "//kopexa.com/frameworks/iso27001@v2.0.0", constraint: "~v1", want: false},
+		{name: "caret minor update", input: "//kopexa.com/frameworks/iso27001@v1.9.0", constraint: "^v1.2.3", want: true},
+		{name: "caret major update", input: "//kopexa.com/frameworks/iso27001@v2.0.0", constraint: "^v1.2.3", want: false},
+		{name: "caret below", input: "//kopexa.com/frameworks/iso27001@v1.2.2", constraint: "^v1.2.3", want: false},
+		{name: "caret zero major", input: "//kopexa.com/frameworks/iso27001@v0.2.9", constraint: "^v0.2.3", want: true},
+		{name: "caret zero major next minor", input: "//kopexa.com/frameworks/iso27001@v0.3.0", constraint: "^v0.2.3", want: false},
+		{name: "caret zero minor", input: "//kopexa.com/frameworks/iso27001@v0.0.3", constraint: "^v0.0.3", want: true},
+		{name: "caret zero minor next patch", input: "//kopexa.com/frameworks/iso27001@v0.0.4", constraint: "^v0.0.3", want: false},
+		{name: "caret zero major two parts", input: "//kopexa.com/frameworks/iso27001@v0.9.0", constraint: "^v0.0", want: false},
+		{name: "caret major only", input: "//kopexa.com/frameworks/iso27001@v0.9.0", constraint: "^v0", want: true},
+		{name: "latest never satisfies", input: "//kopexa.com/frameworks/iso27001@latest", constraint: ">=v1.0.0", want: false},
+		{name: "draft never satisfies", input: "//kopexa.com/frameworks/iso27001@draft", constraint: ">=v0", want: false},
+		{name: "date version never satisfies", input: "//kopexa.com/frameworks/iso27001@2022-01-15", constraint: ">=v1", want: false},
+		{name: "unversioned never satisfies", input: "//kopexa.com/frameworks/iso27001", constraint: ">=v0", want: false},
+		{name: "empty constraint", input: "//kopexa.com/frameworks/iso27001@v1", constraint: "  ", wantErr: ErrInvalidConstraint},
+		{name: "non-semver constraint", input: "//kopexa.com/frameworks/iso27001@v1", constraint: ">=latest", wantErr: ErrInvalidConstraint},
+		{name: "bad operator", input: "//kopexa.com/frameworks/iso27001@v1", constraint: "!v1", wantErr: ErrInvalidConstraint},
+		{name: "invalid constraint on unversioned", input: "//kopexa.com/frameworks/iso27001", constraint: ">=", wantErr: ErrInvalidConstraint},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MustParse(tt.input).VersionSatisfies(tt.constraint)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VersionSatisfies(%q) = %v, want %v", tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortByVersion(t *testing.T) {
+	want := []string{
+		"//kopexa.com/frameworks/iso27001",
+		"//kopexa.com/frameworks/iso27001@draft",
+		"//kopexa.com/frameworks/iso27001@v1",
+		"//kopexa.com/frameworks/iso27001@v2.0.0",
+		"//kopexa.com/frameworks/iso27001@v10",
+		"//kopexa.com/frameworks/iso27001@2022-01-15",
+		"//kopexa.com/frameworks/iso27001@latest",
+	}
+	ks := []*KRN{
+		MustParse(want[6]), nil, MustParse(want[3]), MustParse(want[1]),
+		MustParse(want[5]), MustParse(want[0]), MustParse(want[4]), MustParse(want[2]),
+	}
+
+	SortByVersion(ks)
+	if ks[0] != nil {
+		t.Fatalf("expected nil first, got %s", ks[0])
+	}
+	for i, k := range ks[1:] {
+		if k.String() != want[i] {
+			t.Errorf("position %d: got %s, want %s", i+1, k, want[i])
+		}
+	}
+
+	t.Run("stable for equal versions", func(t *testing.T) {
+		a := MustParse("//kopexa.com/frameworks/nist@v1")
+		b := MustParse("//kopexa.com/frameworks/iso27001@v1")
+		ks := []*KRN{a, b, nil, MustParse("//kopexa.com/frameworks/iso27001@draft"), nil}
+		SortByVersion(ks)
+		if ks[0] != nil || ks[1] != nil || ks[3] != a || ks[4] != b {
+			t.Errorf("SortByVersion() = %v", ks)
+		}
+	})
+}
+
+func TestParseResolved(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+
+	resolver := func(base *KRN) (string, error) {
+		switch base.String() {
+		case "//catalog.kopexa.com/frameworks/iso27001":
+			return "v2.1.0", nil
+		case "//kopexa.com/frameworks/unknown":
+			return "", errLookup
+		case "//kopexa.com/frameworks/floating":
+			return "latest", nil
+		default:
+			return "-invalid", nil
+		}
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "latest is pinned", input: "//catalog.kopexa.com/frameworks/iso27001@latest", want: "//catalog.kopexa.com/frameworks/iso27001@v2.1.0"},
+		{name: "draft is pinned", input: "//catalog.kopexa.com/frameworks/iso27001@draft", want: "//catalog.kopexa.com/frameworks/iso27001@v2.1.0"},
+		{name: "concrete version unchanged", input: "//kopexa.com/frameworks/unknown@v1", want: "//kopexa.com/frameworks/unknown@v1"},
+		{name: "unversioned unchanged", input: "//kopexa.com/frameworks/unknown", want: "//kopexa.com/frameworks/unknown"},
+		{name: "resolver error propagated", input: "//kopexa.com/frameworks/unknown@latest", wantErr: errLookup},
+		{name: "invalid resolved version", input: "//kopexa.com/frameworks/other@latest", wantErr: ErrInvalidVersion},
+		{name: "floating resolved version", input: "//kopexa.com/frameworks/floating@draft", wantErr: ErrInvalidVersion},
+		{name: "parse error", input: "//kopexa.com/frameworks", wantErr: ErrInvalidKRN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResolved(tt.input, resolver)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseResolved(%q) = %q, want %q", tt.input, got.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("resolver receives unversioned base", func(t *testing.T) {
+		var seen *KRN
+		_, err := ParseResolved("//isms.kopexa.com/tenants/acme@latest", func(base *KRN) (string, error) {
+			seen = base
+			return "v1", nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seen == nil || seen.String() != "//isms.kopexa.com/tenants/acme" {
+			t.Errorf("resolver got %v, want //isms.kopexa.com/tenants/acme", seen)
+		}
+	})
+}
+
+func TestKRN_VersionDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		wantOlder string
+		wantNewer string
+		wantSame  bool
+	}{
+		{"newer available", "//kopexa.com/frameworks/iso27001@v1.2.0", "//kopexa.com/frameworks/iso27001@v1.10.0", "v1.2.0", "v1.10.0", true},
+		{"receiver is newer", "//catalog.kopexa.com/frameworks/iso27001@v2", "//catalog.kopexa.com/frameworks/iso27001@v1", "v1", "v2", true},
+		{"latest is newest", "//kopexa.com/frameworks/iso27001@latest", "//kopexa.com/frameworks/iso27001@v9", "v9", "latest", true},
+		{"draft is oldest release", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@draft", "draft", "v1", true},
+		{"unversioned is oldest", "//kopexa.com/frameworks/iso27001@draft", "//kopexa.com/frameworks/iso27001", "", "draft", true},
+		{"equal versions", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v1", "v1", "v1", true},
+		{"different resource", "//kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/nist@v2", "", "", false},
+		{"different service", "//catalog.kopexa.com/frameworks/iso27001@v1", "//kopexa.com/frameworks/iso27001@v2", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			older, newer, same := a.VersionDiff(b)
+			if same != tt.wantSame {
+				t.Fatalf("sameResource = %v, want %v", same, tt.wantSame)
+			}
+			if !same {
+				if older != nil || newer != nil {
+					t.Errorf("expected nil results, got %v and %v", older, newer)
+				}
+				return
+			}
+			if older.Version() != tt.wantOlder || newer.Version() != tt.wantNewer {
+				t.Errorf("VersionDiff() = (%s, %s), want versions (%q, %q)", older, newer, tt.wantOlder, tt.wantNewer)
+			}
+		})
+	}
+
+	t.Run("equal versions return receiver first", func(t *testing.T) {
+		a := MustParse("//kopexa.com/frameworks/iso27001@v1")
+		b := MustParse("//kopexa.com/frameworks/iso27001@v1")
+		older, newer, _ := a.VersionDiff(b)
+		if older != a || newer != b {
+			t.Error("expected (receiver, other) for equal versions")
+		}
+	})
+
+	t.Run("nil other", func(t *testing.T) {
+		older, newer, same := MustParse("//kopexa.com/frameworks/iso27001@v1").VersionDiff(nil)
+		if same || older != nil || newer != nil {
+			t.Error("expected no result for nil other")
+		}
+	})
+}